@@ -0,0 +1,77 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package bot
+
+import "github.com/eightlay/rummikub-server/iternal/game"
+
+// Next event which brings the field closer to the plan
+//
+// Field combinations which are not in the plan are taken to the
+// hand piece by piece, then the planned combinations are added
+// from the hand and the turn is committed
+func nextEvent(state *game.State, plan []*game.Combination) *game.Event {
+	planned := map[string]bool{}
+	for _, c := range plan {
+		planned[game.PiecesKey(c.Pieces)] = true
+	}
+
+	onField := map[string]bool{}
+	for _, c := range state.Field {
+		onField[game.PiecesKey(c.Pieces)] = true
+	}
+
+	if available(state, game.EventTypeInitialMeld) {
+		combinations := [][]string{}
+		for _, c := range plan {
+			if !onField[game.PiecesKey(c.Pieces)] {
+				combinations = append(combinations, game.PiecesIDs(c.Pieces))
+			}
+		}
+
+		return game.NewEvent(game.EventTypeInitialMeld, game.EventInitialMeld{
+			Combinations: combinations,
+		})
+	}
+
+	for _, c := range state.Field {
+		if !planned[game.PiecesKey(c.Pieces)] {
+			return game.NewEvent(game.EventTypeRemovePiece, game.EventRemovePiece{
+				RemovedPiece:     c.Pieces[len(c.Pieces)-1].ID,
+				UsedCombinations: []int{c.ID},
+			})
+		}
+	}
+
+	for _, c := range plan {
+		if !onField[game.PiecesKey(c.Pieces)] {
+			return game.NewEvent(game.EventTypeAddCombination, game.EventAddCombination{
+				AddedPieces: game.PiecesIDs(c.Pieces),
+			})
+		}
+	}
+
+	return game.NewEvent(game.EventTypeCommitTurn, nil)
+}
+
+// Value of the planned combinations which are not on the table
+//
+// Jokers count as the pieces they represent
+func meldValue(plan []*game.Combination, table [][]*game.Piece) int {
+	onTable := map[string]bool{}
+	for _, pieces := range table {
+		onTable[game.PiecesKey(pieces)] = true
+	}
+
+	value := 0
+	for _, c := range plan {
+		if onTable[game.PiecesKey(c.Pieces)] {
+			continue
+		}
+
+		value += c.Value()
+	}
+
+	return value
+}
@@ -0,0 +1,124 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package bot
+
+import (
+	"fmt"
+
+	"github.com/eightlay/rummikub-server/iternal/game"
+)
+
+// Bot player
+//
+// Gets the same state a client gets and answers with the next
+// event to be handled, nil if there is nothing to do
+type Player interface {
+	Play(state *game.State) *game.Event
+}
+
+// Difficulty level of the bot
+type Difficulty string
+
+const (
+	// Plays one combination from the hand
+	DifficultyEasy Difficulty = "easy"
+	// Plays combinations from the hand and rearranges
+	// table combinations one at a time to add pieces
+	DifficultyMedium Difficulty = "medium"
+	// Rearranges the whole table with the solver
+	DifficultyHard Difficulty = "hard"
+)
+
+// Create bot player of the difficulty
+func New(d Difficulty) (Player, error) {
+	switch d {
+	case DifficultyEasy:
+		return &bot{planner: greedyPlan}, nil
+	case DifficultyMedium:
+		return &bot{planner: rearrangingPlan}, nil
+	case DifficultyHard:
+		return &bot{planner: solverPlan}, nil
+	}
+	return nil, fmt.Errorf("there is no bot difficulty: %v", d)
+}
+
+// Planner of the turn
+//
+// Returns the field the player wants to get by the end of the turn,
+// nil if the player can't play any piece from the hand
+type planner func(rules game.RuleSet, hand []*game.Piece, table [][]*game.Piece, meld bool) []*game.Combination
+
+// Bot player following the planned field
+//
+// The plan is made at the beginning of the turn and is
+// carried out by one event at a time
+type bot struct {
+	planner planner
+	plan    []*game.Combination
+}
+
+func (b *bot) Play(state *game.State) *game.Event {
+	if !state.Turn {
+		return nil
+	}
+
+	// Nothing is changed yet, so the turn has just begun
+	if !available(state, game.EventTypeCommitTurn) {
+		b.plan = b.makePlan(state)
+	}
+
+	if b.plan == nil {
+		return Fallback(state)
+	}
+
+	return nextEvent(state, b.plan)
+}
+
+// Make the plan of the turn from the state
+func (b *bot) makePlan(state *game.State) []*game.Combination {
+	meld := available(state, game.EventTypeInitialMeld)
+	if !meld && !available(state, game.EventTypeAddCombination) {
+		return nil
+	}
+
+	table := [][]*game.Piece{}
+	for _, c := range state.Field {
+		table = append(table, c.Pieces)
+	}
+
+	plan := b.planner(state.Rules, []*game.Piece(state.Hand), table, meld)
+	if meld && plan != nil && meldValue(plan, table) < state.Rules.InitialMeldSum {
+		return nil
+	}
+
+	return plan
+}
+
+// Event which finishes the turn without playing
+//
+// Reverts the changes of the turn first
+func Fallback(state *game.State) *game.Event {
+	switch {
+	case !state.Turn:
+		return nil
+	case available(state, game.EventTypeRevertTurn):
+		return game.NewEvent(game.EventTypeRevertTurn, nil)
+	case available(state, game.EventTypeDraw):
+		return game.NewEvent(game.EventTypeDraw, nil)
+	case available(state, game.EventTypePass):
+		return game.NewEvent(game.EventTypePass, nil)
+	}
+	return nil
+}
+
+// Check if the event is available in the state
+func available(state *game.State, t game.EventType) bool {
+	for _, e := range state.AvailableEvents {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
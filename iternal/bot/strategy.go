@@ -0,0 +1,156 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package bot
+
+import (
+	"time"
+
+	"github.com/eightlay/rummikub-server/iternal/game"
+	"github.com/eightlay/rummikub-server/iternal/solver"
+)
+
+const (
+	// Search limit of the hand's combinations
+	handNodes = 20000
+
+	// Search limit of the rearrangement of one table combination
+	combinationNodes = 2000
+
+	// Time budget of the whole table rearrangement
+	solverBudget = 2 * time.Second
+)
+
+// Plan one combination from the hand
+//
+// The most valuable one for the initial meld,
+// the largest one otherwise
+func greedyPlan(rules game.RuleSet, hand []*game.Piece, table [][]*game.Piece, meld bool) []*game.Combination {
+	combinations := handCombinations(rules, hand, meld)
+	if len(combinations) == 0 {
+		return nil
+	}
+
+	best := combinations[0]
+	for _, c := range combinations[1:] {
+		if better(c, best, meld) {
+			best = c
+		}
+	}
+
+	return append(tableCombinations(rules, table), best)
+}
+
+// Check if the combination is better than the other one
+func better(c *game.Combination, other *game.Combination, meld bool) bool {
+	value := meldValue([]*game.Combination{c}, nil)
+	otherValue := meldValue([]*game.Combination{other}, nil)
+
+	if meld && value != otherValue {
+		return value > otherValue
+	}
+	if len(c.Pieces) != len(other.Pieces) {
+		return len(c.Pieces) > len(other.Pieces)
+	}
+	return value > otherValue
+}
+
+// Plan combinations from the hand and add the rest of the pieces
+// to the table rearranging one table combination at a time
+func rearrangingPlan(rules game.RuleSet, hand []*game.Piece, table [][]*game.Piece, meld bool) []*game.Combination {
+	combinations := handCombinations(rules, hand, meld)
+	if meld {
+		if len(combinations) == 0 {
+			return nil
+		}
+		return append(tableCombinations(rules, table), combinations...)
+	}
+
+	played := map[*game.Piece]bool{}
+	for _, c := range combinations {
+		for _, p := range c.Pieces {
+			played[p] = true
+		}
+	}
+
+	plan := tableCombinations(rules, table)
+
+	for _, p := range hand {
+		if played[p] {
+			continue
+		}
+
+		for i, c := range plan {
+			s, err := solver.Solve(
+				rules, []*game.Piece{p}, [][]*game.Piece{c.Pieces},
+				solver.Options{MaxNodes: combinationNodes},
+			)
+			if err != nil || s.Pieces == 0 {
+				continue
+			}
+
+			plan = append(append(plan[:i:i], s.Combinations...), plan[i+1:]...)
+			played[p] = true
+			break
+		}
+	}
+
+	if len(played) == 0 {
+		return nil
+	}
+
+	return append(plan, combinations...)
+}
+
+// Plan the best rearrangement of the whole table
+//
+// Only the hand's combinations can be played as the initial meld
+func solverPlan(rules game.RuleSet, hand []*game.Piece, table [][]*game.Piece, meld bool) []*game.Combination {
+	if meld {
+		s, err := solver.Solve(rules, hand, nil, solver.Options{
+			Goal: solver.MaxPoints, Budget: solverBudget,
+		})
+		if err != nil || s.Pieces == 0 {
+			return nil
+		}
+		return append(tableCombinations(rules, table), s.Combinations...)
+	}
+
+	s, err := solver.Solve(rules, hand, table, solver.Options{
+		Goal: solver.MaxPieces, Budget: solverBudget,
+	})
+	if err != nil || s.Pieces == 0 {
+		return nil
+	}
+
+	return s.Combinations
+}
+
+// Combinations which can be made of the hand's pieces only
+func handCombinations(rules game.RuleSet, hand []*game.Piece, meld bool) []*game.Combination {
+	goal := solver.MaxPieces
+	if meld {
+		goal = solver.MaxPoints
+	}
+
+	s, err := solver.Solve(rules, hand, nil, solver.Options{
+		Goal: goal, MaxNodes: handNodes,
+	})
+	if err != nil {
+		return nil
+	}
+
+	return s.Combinations
+}
+
+// Table combinations as they are
+func tableCombinations(rules game.RuleSet, table [][]*game.Piece) []*game.Combination {
+	combinations := []*game.Combination{}
+	for _, pieces := range table {
+		if c := rules.ValidCombination(pieces, nil); c != nil {
+			combinations = append(combinations, c)
+		}
+	}
+	return combinations
+}
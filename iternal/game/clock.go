@@ -0,0 +1,23 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package game
+
+import "time"
+
+// Clock
+//
+// Provides current time to the game, so the turn timer
+// can be driven by something other than the system time
+type Clock interface {
+	Now() time.Time
+}
+
+// System time clock
+type systemClock struct{}
+
+// Current system time
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
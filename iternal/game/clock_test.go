@@ -0,0 +1,112 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package game
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// Clock moved by the test
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// Move the clock forward
+func (c *fakeClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestTurnTimeout(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	g := newTestGame(t)
+	g.SetClock(clock)
+	startGame(t, g, "a", "b")
+
+	limit := g.rules.TimeLimitSeconds
+	current, other := player(g.CurrentPlayer()), player(otherPlayer(g))
+
+	// Time left counts down, started seconds are rounded up
+	countdown := []struct {
+		advance time.Duration
+		want    int
+	}{
+		{0, limit},
+		{500 * time.Millisecond, limit},
+		{500 * time.Millisecond, limit - 1},
+		{10 * time.Second, limit - 11},
+		{time.Duration(limit-12) * time.Second, 1},
+	}
+
+	for _, c := range countdown {
+		clock.advance(c.advance)
+
+		if left := g.State(string(current)).TimeLeft; left != c.want {
+			t.Errorf("%v seconds left at %v, want %v", left, clock.now, c.want)
+		}
+		if g.CheckTimeout() {
+			t.Fatalf("turn is finished with %v seconds left", c.want)
+		}
+	}
+
+	// Uncommitted draft of the current player
+	g.stages[current] = mainGameStage
+	hand := PiecesIDs(g.hands[current])
+	piece := g.hands[current][0]
+
+	added, _ := json.Marshal(EventAddCombination{AddedPieces: []string{piece.ID}})
+	if err := g.HandleEvent(string(current), &Event{Type: EventTypeAddCombination, Data: added}).Err(); err != nil {
+		t.Fatalf("combination is not added: %v", err)
+	}
+
+	penalty := PiecesIDs(g.bank[:g.rules.PenaltySize])
+	bank := len(g.bank)
+	otherHand := fmt.Sprint(PiecesIDs(g.hands[other]))
+
+	clock.advance(time.Second)
+
+	if left := g.State(string(current)).TimeLeft; left != 0 {
+		t.Errorf("%v seconds left after the deadline, want 0", left)
+	}
+	if !g.CheckTimeout() {
+		t.Fatalf("turn is not finished after the deadline")
+	}
+
+	if len(g.field) != 0 {
+		t.Errorf("uncommitted combinations are left on the field: %v", g.field.ordered())
+	}
+
+	// The reverted hand gets the penalty pieces from the top of the bank
+	want := fmt.Sprint(append(hand, penalty...))
+	if got := fmt.Sprint(PiecesIDs(g.hands[current])); got != want {
+		t.Errorf("hand after the timeout is %v, want %v", got, want)
+	}
+	if n := len(g.bank); n != bank-g.rules.PenaltySize {
+		t.Errorf("bank has %v pieces after the penalty, want %v", n, bank-g.rules.PenaltySize)
+	}
+	if got := fmt.Sprint(PiecesIDs(g.hands[other])); got != otherHand {
+		t.Errorf("penalty is drawn to the next player's hand %v, was %v", got, otherHand)
+	}
+
+	if p := player(g.CurrentPlayer()); p != other {
+		t.Errorf("turn passed to %v, want %v", p, other)
+	}
+	if left := g.State(string(other)).TimeLeft; left != limit {
+		t.Errorf("next turn has %v seconds left, want %v", left, limit)
+	}
+
+	entry := g.history.log[len(g.history.log)-1]
+	if entry.Type != EventTypeTimeout || entry.Player != current {
+		t.Errorf("last log entry is %v of %v, want %v of %v", entry.Type, entry.Player, EventTypeTimeout, current)
+	}
+}
@@ -4,8 +4,6 @@
 
 package game
 
-import mapset "github.com/deckarep/golang-set/v2"
-
 // Pice color
 type color string
 
@@ -22,6 +20,3 @@ const (
 
 // All colors
 var colors []color = []color{black, red, blue, orange}
-
-// All colors set
-var colorsSet mapset.Set[color] = mapset.NewSet(colors...)
@@ -4,10 +4,6 @@
 
 package game
 
-import (
-	mapset "github.com/deckarep/golang-set/v2"
-)
-
 // Combination type
 //
 // Existing types: group, run
@@ -18,169 +14,427 @@ const (
 	group combinationType = "G"
 	// Run combination type
 	run combinationType = "R"
+	// Draft combination type (not validated yet)
+	draft combinationType = "D"
 )
 
+// Value represented by the joker in the combination
+type JokerAssignment struct {
+	Number int   `json:"number"`
+	Color  color `json:"color"`
+}
+
 // Combination
 //
-// Contains information about used pieces and combination type
+// Contains information about used pieces and combination type.
+// Jokers keep their own number and color, the values they represent
+// are stored by their ids
 type Combination struct {
-	Pieces pack            `json:"pieces"`
-	Type   combinationType `json:"type"`
+	Pieces pack                       `json:"pieces"`
+	Type   combinationType            `json:"type"`
+	Jokers map[string]JokerAssignment `json:"jokers,omitempty"`
 }
 
-// Returns combination if provided pieces present valid initial meld
-func validInitialMeld(pieces []*Piece) *Combination {
-	newCombination := validCombination(pieces)
-
-	if newCombination != nil {
-		s := 0
+// Copy combination, so the copy can be changed independently
+func (c *Combination) copy() *Combination {
+	jokers := map[string]JokerAssignment{}
+	for id, j := range c.Jokers {
+		jokers[id] = j
+	}
 
-		for _, p := range newCombination.Pieces {
-			s += p.Number
-		}
+	return &Combination{
+		Pieces: append(pack{}, c.Pieces...),
+		Type:   c.Type,
+		Jokers: jokers,
+	}
+}
 
-		correct := s >= InitialMeldSum
+// Sum of the numbers represented by the combination's pieces
+func (c *Combination) Value() int {
+	sum := 0
 
-		if correct {
-			return newCombination
+	for _, p := range c.Pieces {
+		if j, ok := c.Jokers[p.ID]; ok {
+			sum += j.Number
+		} else {
+			sum += p.Number
 		}
 	}
 
-	return nil
+	return sum
 }
 
-// Return combination if provided pieces present valid combination
-func validCombination(pieces []*Piece) *Combination {
-	validGroup := isValidGroup(pieces)
+// Returns combinations if provided pieces present valid initial meld
+//
+// Every combination must be valid and their values together
+// must reach the initial meld sum
+func (r *RuleSet) validInitialMeld(
+	pieces [][]*Piece, declared map[string]JokerAssignment,
+) ([]*Combination, error) {
+	combinations := []*Combination{}
+	sum := 0
+
+	for i, p := range pieces {
+		combination := r.validCombination(p, declared)
+		if combination == nil {
+			return nil, NewError(
+				ErrorCodeInvalidCombination, "combination %v is invalid", i,
+			)
+		}
 
-	if !validGroup {
-		validRun := isValidRun(pieces)
+		combinations = append(combinations, combination)
+		sum += combination.Value()
+	}
 
-		if !validRun {
+	if sum < r.InitialMeldSum {
+		return nil, NewError(
+			ErrorCodeInitialMeldSum,
+			"initial meld sum %v is less than %v", sum, r.InitialMeldSum,
+		)
+	}
 
-			for _, p := range pieces {
-				p.clearIfJoker()
-			}
+	return combinations, nil
+}
 
-			return nil
-		} else {
-			return &Combination{
-				Pieces: sortPieces(pieces),
-				Type:   run,
-			}
+// Return draft combination made of the provided pieces
+//
+// Keeps the declared values of the jokers which are in the pieces
+func draftCombination(pieces []*Piece, declared map[string]JokerAssignment) *Combination {
+	jokers := map[string]JokerAssignment{}
+
+	for _, p := range pieces {
+		if j, ok := declared[p.ID]; ok && p.Joker {
+			jokers[p.ID] = j
 		}
 	}
 
 	return &Combination{
-		Pieces: sortPieces(pieces),
-		Type:   group,
+		Pieces: pieces,
+		Type:   draft,
+		Jokers: jokers,
 	}
 }
 
-// Check if provided pieces present valid group
-func isValidGroup(pieces []*Piece) bool {
-	if len(pieces) < MinGroupSize || len(pieces) > MaxGroupSize {
+// Declared values of the jokers
+//
+// Validated combinations declare the values their jokers represent,
+// so the values are carried to the combinations made of them
+func (c *Combination) declarations() map[string]JokerAssignment {
+	return c.Jokers
+}
+
+// Return combination if provided pieces present valid combination,
+// nil otherwise
+//
+// Declared values of the jokers are optional
+func (r RuleSet) ValidCombination(
+	pieces []*Piece, declared map[string]JokerAssignment,
+) *Combination {
+	return r.validCombination(pieces, declared)
+}
+
+// Return combination if provided pieces present valid combination
+//
+// The pieces are not changed. The jokers represent the declared values,
+// undeclared jokers get the values of the first legal interpretation
+func (r *RuleSet) validCombination(
+	pieces []*Piece, declared map[string]JokerAssignment,
+) *Combination {
+	var found *Combination
+
+	r.visitInterpretations(pieces, declared, func(c *Combination) bool {
+		found = c
 		return false
+	})
+
+	return found
+}
+
+// Return combination if provided pieces present valid combination
+// with the declared jokers, nil otherwise
+//
+// Of the valid interpretations the one keeping the most preferred
+// values is chosen, so the jokers keep the values they had on the
+// field unless the values no longer fit
+func (r *RuleSet) preferredCombination(
+	pieces []*Piece, declared map[string]JokerAssignment,
+	preferred map[string]JokerAssignment,
+) *Combination {
+	var best *Combination
+	bestKept := -1
+
+	r.visitInterpretations(pieces, declared, func(c *Combination) bool {
+		kept := 0
+		for id, j := range c.Jokers {
+			if v, ok := preferred[id]; ok && v == j {
+				kept += 1
+			}
+		}
+
+		if kept > bestKept {
+			best = c
+			bestKept = kept
+		}
+
+		return true
+	})
+
+	return best
+}
+
+// All legal interpretations of the provided pieces
+//
+// Groups go first, then runs from the rightmost one. Jokers are
+// assigned in the given order, so the first interpretation fills
+// the gaps of a run first and then extends it to the right
+func (r *RuleSet) interpretations(pieces []*Piece) []*Combination {
+	return collect(func(visit func(c *Combination) bool) {
+		r.visitInterpretations(pieces, nil, visit)
+	})
+}
+
+// Visit legal interpretations of the provided pieces in order
+//
+// Declared jokers represent only the declared values. The visit
+// stops when visit returns false, then false is returned
+func (r *RuleSet) visitInterpretations(
+	pieces []*Piece, declared map[string]JokerAssignment, visit func(c *Combination) bool,
+) bool {
+	return r.visitGroups(pieces, declared, visit) && r.visitRuns(pieces, declared, visit)
+}
+
+// Combinations visited by the walk
+func collect(walk func(visit func(c *Combination) bool)) []*Combination {
+	combinations := []*Combination{}
+
+	walk(func(c *Combination) bool {
+		combinations = append(combinations, c)
+		return true
+	})
+
+	return combinations
+}
+
+// All legal interpretations of the provided pieces as a group
+func (r *RuleSet) groupInterpretations(pieces []*Piece) []*Combination {
+	return collect(func(visit func(c *Combination) bool) {
+		r.visitGroups(pieces, nil, visit)
+	})
+}
+
+// Visit legal interpretations of the provided pieces as a group
+func (r *RuleSet) visitGroups(
+	pieces []*Piece, declared map[string]JokerAssignment, visit func(c *Combination) bool,
+) bool {
+	if len(pieces) < r.MinGroupSize || len(pieces) > r.MaxGroupSize {
+		return true
 	}
 
-	usedColors := mapset.NewSet[color]()
-	var number int = JokerNumber
+	number := JokerNumber
+	byColor := map[color]*Piece{}
+	jokers := []*Piece{}
 
 	for _, p := range pieces {
 		if p.Joker {
+			jokers = append(jokers, p)
 			continue
 		}
 
-		if number == 0 {
+		if number == JokerNumber {
 			number = p.Number
 		} else if number != p.Number {
-			return false
-		} else if usedColors.Contains(p.Color) {
-			return false
+			return true
 		}
 
-		usedColors.Add(p.Color)
+		if _, ok := byColor[p.Color]; ok {
+			return true
+		}
+
+		byColor[p.Color] = p
 	}
 
-	for _, p := range pieces {
-		if p.Joker {
-			c, _ := colorsSet.Difference(usedColors).Pop()
-			p.Number = number
-			p.Color = c
+	// Group of jokers only doesn't represent any number
+	if number == JokerNumber {
+		return true
+	}
+
+	slots := []JokerAssignment{}
+	for _, c := range colors {
+		if _, ok := byColor[c]; !ok {
+			slots = append(slots, JokerAssignment{number, c})
 		}
 	}
 
-	return true
+	return visitJokerAssignments(jokers, slots, declared, func(jokers_ map[string]JokerAssignment) bool {
+		combination := &Combination{
+			Pieces: pack{},
+			Type:   group,
+			Jokers: map[string]JokerAssignment{},
+		}
+
+		for _, c := range colors {
+			if p, ok := byColor[c]; ok {
+				combination.Pieces = append(combination.Pieces, p)
+				continue
+			}
+
+			for _, j := range jokers {
+				if jokers_[j.ID].Color == c {
+					combination.Pieces = append(combination.Pieces, j)
+					combination.Jokers[j.ID] = jokers_[j.ID]
+				}
+			}
+		}
+
+		return visit(combination)
+	})
 }
 
-// Check if provided pieces present valid run
-func isValidRun(pieces_ []*Piece) bool {
-	if len(pieces_) < MinRunSize {
-		return false
+// All legal interpretations of the provided pieces as a run
+func (r *RuleSet) runInterpretations(pieces []*Piece) []*Combination {
+	return collect(func(visit func(c *Combination) bool) {
+		r.visitRuns(pieces, nil, visit)
+	})
+}
+
+// Visit legal interpretations of the provided pieces as a run
+//
+// Wrapping from 13 to 1 is not allowed
+func (r *RuleSet) visitRuns(
+	pieces []*Piece, declared map[string]JokerAssignment, visit func(c *Combination) bool,
+) bool {
+	if len(pieces) < r.MinRunSize || len(pieces) > MaxNumber-MinNumber+1 {
+		return true
 	}
 
-	pieces := sortPieces(pieces_)
+	runColor := JokerColor
+	byNumber := map[int]*Piece{}
+	jokers := []*Piece{}
+	first, last := MaxNumber, MinNumber
 
-	runColor := pieces[len(pieces)-1].Color
+	for _, p := range pieces {
+		if p.Joker {
+			jokers = append(jokers, p)
+			continue
+		}
 
-	jokerCount := 0
-	jokerValues := []int{}
-	startIndex := 1
+		if runColor == JokerColor {
+			runColor = p.Color
+		} else if p.Color != runColor {
+			return true
+		}
+
+		if _, ok := byNumber[p.Number]; ok {
+			return true
+		}
 
-	if pieces[0].Joker {
-		startIndex += 1
-		jokerCount += 1
+		byNumber[p.Number] = p
+
+		if p.Number < first {
+			first = p.Number
+		}
+		if p.Number > last {
+			last = p.Number
+		}
 	}
 
-	if pieces[1].Joker {
-		startIndex += 1
-		jokerCount += 1
+	// Run of jokers only doesn't represent any numbers
+	if len(byNumber) == 0 {
+		return true
 	}
 
-	var lastNumber = pieces[startIndex-1].Number
+	// Every start of the run which covers all the numbers
+	for start := first; start >= MinNumber; start-- {
+		end := start + len(pieces) - 1
+		if end < last {
+			break
+		}
+		if end > MaxNumber {
+			continue
+		}
 
-	for i := startIndex; i < len(pieces); i++ {
-		if pieces[i].Color != runColor {
-			return false
+		slots := []JokerAssignment{}
+		for n := start; n <= end; n++ {
+			if _, ok := byNumber[n]; !ok {
+				slots = append(slots, JokerAssignment{n, runColor})
+			}
 		}
 
-		diff := pieces[i].Number - lastNumber
+		visited := visitJokerAssignments(jokers, slots, declared, func(jokers_ map[string]JokerAssignment) bool {
+			combination := &Combination{
+				Pieces: pack{},
+				Type:   run,
+				Jokers: jokers_,
+			}
 
-		if diff <= 0 {
+			for n := start; n <= end; n++ {
+				if p, ok := byNumber[n]; ok {
+					combination.Pieces = append(combination.Pieces, p)
+					continue
+				}
+
+				for _, j := range jokers {
+					if jokers_[j.ID].Number == n {
+						combination.Pieces = append(combination.Pieces, j)
+					}
+				}
+			}
+
+			return visit(combination)
+		})
+
+		if !visited {
 			return false
 		}
+	}
 
-		if diff != 1 {
-			if jokerCount == 0 {
-				return false
+	return true
+}
+
+// Visit every way to assign the jokers to different slots
+//
+// Assignments are visited in order, so the first one gives the slots
+// to the jokers in the given order. Declared jokers take only the
+// declared slots. The visit stops when visit returns false, then
+// false is returned
+func visitJokerAssignments(
+	jokers []*Piece, slots []JokerAssignment, declared map[string]JokerAssignment,
+	visit func(jokers map[string]JokerAssignment) bool,
+) bool {
+	assignment := map[string]JokerAssignment{}
+	used := make([]bool, len(slots))
+
+	var assign func(i int) bool
+	assign = func(i int) bool {
+		if i == len(jokers) {
+			a := map[string]JokerAssignment{}
+			for id, j := range assignment {
+				a[id] = j
 			}
+			return visit(a)
+		}
 
-			jokersNeeded := diff - 1
+		joker := jokers[i]
+		value, ok := declared[joker.ID]
 
-			if jokersNeeded > jokerCount {
-				return false
+		for s, slot := range slots {
+			if used[s] || ok && slot != value {
+				continue
 			}
 
-			jokerCount -= jokersNeeded
+			used[s] = true
+			assignment[joker.ID] = slot
+			visited := assign(i + 1)
+			used[s] = false
+			delete(assignment, joker.ID)
 
-			for j := 1; j <= jokersNeeded; j++ {
-				lastNumber += 1
-				jokerValues = append(jokerValues, lastNumber)
+			if !visited {
+				return false
 			}
 		}
 
-		lastNumber = pieces[i].Number
-	}
-
-	for i := 0; i < jokerCount; i++ {
-		lastNumber += 1
-		jokerValues = append(jokerValues, lastNumber)
+		return true
 	}
 
-	for i, v := range jokerValues {
-		pieces[i].Number = v
-		pieces[i].Color = runColor
-	}
-
-	return true
+	return assign(0)
 }
@@ -0,0 +1,264 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package game
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Piece of the given deck
+func testPiece(deck int, color_ color, number int) *Piece {
+	return createPiece(pieceID(deck, color_, number), number, color_, false)
+}
+
+// Joker of the first deck
+func testJoker(number int) *Piece {
+	return createPiece(pieceID(1, JokerColor, number), JokerNumber, JokerColor, true)
+}
+
+func TestCombinationInterpretations(t *testing.T) {
+	k5 := testPiece(1, black, 5)
+	r1 := testPiece(1, red, 1)
+	r2 := testPiece(1, red, 2)
+	r5 := testPiece(1, red, 5)
+	r5b := testPiece(2, red, 5)
+	r6 := testPiece(1, red, 6)
+	r12 := testPiece(1, red, 12)
+	r13 := testPiece(1, red, 13)
+	b5 := testPiece(1, blue, 5)
+	b6 := testPiece(1, blue, 6)
+	o5 := testPiece(1, orange, 5)
+	j1 := testJoker(1)
+	j2 := testJoker(2)
+
+	tests := []struct {
+		name     string
+		pieces   []*Piece
+		declared map[string]JokerAssignment
+
+		// First interpretation matching the declared values, nil if invalid
+		want *Combination
+		// Numbers of the interpretations
+		groups int
+		runs   int
+	}{
+		{
+			name:   "group",
+			pieces: []*Piece{r5, b5, k5},
+			want:   &Combination{Pieces: pack{k5, r5, b5}, Type: group},
+			groups: 1,
+		},
+		{
+			name:   "group with joker",
+			pieces: []*Piece{j1, r5, k5},
+			want: &Combination{
+				Pieces: pack{k5, r5, j1},
+				Type:   group,
+				Jokers: map[string]JokerAssignment{j1.ID: {5, blue}},
+			},
+			groups: 2,
+		},
+		{
+			name:     "group with declared joker",
+			pieces:   []*Piece{k5, r5, j1},
+			declared: map[string]JokerAssignment{j1.ID: {5, orange}},
+			want: &Combination{
+				Pieces: pack{k5, r5, j1},
+				Type:   group,
+				Jokers: map[string]JokerAssignment{j1.ID: {5, orange}},
+			},
+			groups: 2,
+		},
+		{
+			name:   "group with two jokers",
+			pieces: []*Piece{j2, k5, j1},
+			want: &Combination{
+				Pieces: pack{k5, j2, j1},
+				Type:   group,
+				Jokers: map[string]JokerAssignment{
+					j2.ID: {5, red},
+					j1.ID: {5, blue},
+				},
+			},
+			groups: 6,
+			runs:   6,
+		},
+		{
+			name:   "group of too many pieces",
+			pieces: []*Piece{k5, r5, b5, o5, j1},
+		},
+		{
+			name:   "group with duplicate color",
+			pieces: []*Piece{k5, r5, r5b},
+		},
+		{
+			name:     "group with joker declared in taken color",
+			pieces:   []*Piece{k5, r5, j1},
+			declared: map[string]JokerAssignment{j1.ID: {5, red}},
+			groups:   2,
+		},
+		{
+			name:   "run",
+			pieces: []*Piece{r6, r5, testPiece(1, red, 4)},
+			want: &Combination{
+				Pieces: pack{testPiece(1, red, 4), r5, r6},
+				Type:   run,
+			},
+			runs: 1,
+		},
+		{
+			name:   "run with joker at the end",
+			pieces: []*Piece{r5, r6, j1},
+			want: &Combination{
+				Pieces: pack{r5, r6, j1},
+				Type:   run,
+				Jokers: map[string]JokerAssignment{j1.ID: {7, red}},
+			},
+			runs: 2,
+		},
+		{
+			name:     "run with joker declared at the beginning",
+			pieces:   []*Piece{r5, r6, j1},
+			declared: map[string]JokerAssignment{j1.ID: {4, red}},
+			want: &Combination{
+				Pieces: pack{j1, r5, r6},
+				Type:   run,
+				Jokers: map[string]JokerAssignment{j1.ID: {4, red}},
+			},
+			runs: 2,
+		},
+		{
+			name:   "run with joker before the last number",
+			pieces: []*Piece{r12, r13, j1},
+			want: &Combination{
+				Pieces: pack{j1, r12, r13},
+				Type:   run,
+				Jokers: map[string]JokerAssignment{j1.ID: {11, red}},
+			},
+			runs: 1,
+		},
+		{
+			name:   "run with joker after the first number",
+			pieces: []*Piece{j1, r1, r2},
+			want: &Combination{
+				Pieces: pack{r1, r2, j1},
+				Type:   run,
+				Jokers: map[string]JokerAssignment{j1.ID: {3, red}},
+			},
+			runs: 1,
+		},
+		{
+			name:     "run with two jokers",
+			pieces:   []*Piece{r5, j1, j2},
+			declared: map[string]JokerAssignment{j1.ID: {6, red}},
+			want: &Combination{
+				Pieces: pack{r5, j1, j2},
+				Type:   run,
+				Jokers: map[string]JokerAssignment{
+					j1.ID: {6, red},
+					j2.ID: {7, red},
+				},
+			},
+			groups: 6,
+			runs:   6,
+		},
+		{
+			name:     "run with jokers at both ends",
+			pieces:   []*Piece{j1, r5, r6, j2},
+			declared: map[string]JokerAssignment{j1.ID: {4, red}},
+			want: &Combination{
+				Pieces: pack{j1, r5, r6, j2},
+				Type:   run,
+				Jokers: map[string]JokerAssignment{
+					j1.ID: {4, red},
+					j2.ID: {7, red},
+				},
+			},
+			runs: 6,
+		},
+		{
+			name:     "run with joker declared out of the run",
+			pieces:   []*Piece{r5, r6, j1},
+			declared: map[string]JokerAssignment{j1.ID: {9, red}},
+			runs:     2,
+		},
+		{
+			name:   "run wrapping from 13 to 1",
+			pieces: []*Piece{r12, r13, r1},
+		},
+		{
+			name:   "run wrapping from 13 to 2",
+			pieces: []*Piece{r13, r1, r2},
+		},
+		{
+			name:   "run wrapping with joker",
+			pieces: []*Piece{r13, j1, r1},
+		},
+		{
+			name:   "run with duplicate number",
+			pieces: []*Piece{r5, r5b, r6},
+		},
+		{
+			name:   "run of different colors",
+			pieces: []*Piece{r5, b6, testPiece(1, red, 7)},
+		},
+		{
+			name:   "jokers only",
+			pieces: []*Piece{j1, j2, testJoker(3)},
+		},
+	}
+
+	rules := DefaultRuleSet()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			order := append([]*Piece{}, test.pieces...)
+			values := []Piece{}
+			for _, p := range test.pieces {
+				values = append(values, *p)
+			}
+
+			groups := rules.groupInterpretations(test.pieces)
+			runs := rules.runInterpretations(test.pieces)
+			got := rules.validCombination(test.pieces, test.declared)
+
+			if len(groups) != test.groups {
+				t.Errorf("got %v group interpretations, want %v", len(groups), test.groups)
+			}
+			if len(runs) != test.runs {
+				t.Errorf("got %v run interpretations, want %v", len(runs), test.runs)
+			}
+
+			switch {
+			case test.want == nil && got != nil:
+				t.Errorf("got combination %v, want none", PiecesIDs(got.Pieces))
+			case test.want != nil && got == nil:
+				t.Errorf("got no combination, want %v", PiecesIDs(test.want.Pieces))
+			case test.want != nil:
+				if got.Type != test.want.Type {
+					t.Errorf("got type %v, want %v", got.Type, test.want.Type)
+				}
+				if !reflect.DeepEqual(PiecesIDs(got.Pieces), PiecesIDs(test.want.Pieces)) {
+					t.Errorf("got pieces %v, want %v", PiecesIDs(got.Pieces), PiecesIDs(test.want.Pieces))
+				}
+				if len(got.Jokers) != len(test.want.Jokers) ||
+					len(got.Jokers) > 0 && !reflect.DeepEqual(got.Jokers, test.want.Jokers) {
+					t.Errorf("got jokers %v, want %v", got.Jokers, test.want.Jokers)
+				}
+			}
+
+			// Input pieces are never changed
+			for i, p := range test.pieces {
+				if p != order[i] {
+					t.Errorf("piece %v is moved", i)
+				}
+				if *p != values[i] {
+					t.Errorf("piece %v is changed: %v, was %v", i, *p, values[i])
+				}
+			}
+		})
+	}
+}
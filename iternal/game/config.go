@@ -5,9 +5,6 @@
 package game
 
 const (
-	// Number of pieces a player has at the beginning
-	HandSize int = 14
-
 	// Minimal number on a piece
 	MinNumber int = 1
 	// Maximal number on a piece
@@ -17,27 +14,8 @@ const (
 	JokerNumber int = 0
 	// Color of a joker piece
 	JokerColor color = "jokerColor"
-
-	// Number of decks in the bank at the beginning
-	DecksNumber int = 2
-
-	// Time limit for a move
-	TimeLimitSeconds int = 60
-	// Penalty size (in pieces) for exceeding time limit for a move
-	PenaltySize int = 3
-
-	// Initial meld sum minimal value
-	InitialMeldSum int = 30
-
-	// Minimal size of the group combination type
-	MinGroupSize int = 3
-	// Maximal size of the group combination type
-	MaxGroupSize int = 4
-	// Minimal size of the run combination type
-	MinRunSize int = 3
-
-	// Minimal number of players in the game
-	MinPlayersNumber = 2
-	// Maximal number of players in the game
-	MaxPlayersNumber = 4
+	// Value of a joker piece left in the hand at the end of the game
+	JokerValue int = 30
+	// Maximal number of jokers in a deck
+	MaxJokersPerDeck int = 4
 )
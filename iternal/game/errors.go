@@ -0,0 +1,102 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package game
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error code
+//
+// Machine readable reason of the rejected event
+type ErrorCode string
+
+const (
+	// Error without a specific code
+	ErrorCodeRejected ErrorCode = "rejected"
+	// Event data can't be decoded or contains unknown fields
+	ErrorCodeInvalidData ErrorCode = "invalidData"
+	// There is no event with the given type
+	ErrorCodeUnknownEvent ErrorCode = "unknownEvent"
+	// There is no player with the given name
+	ErrorCodeUnknownPlayer ErrorCode = "unknownPlayer"
+	// Number of players is out of the rule set limits
+	ErrorCodePlayersNumber ErrorCode = "playersNumber"
+	// Game is not started yet
+	ErrorCodeNotStarted ErrorCode = "notStarted"
+	// Action is not allowed in the game's phase
+	ErrorCodeWrongPhase ErrorCode = "wrongPhase"
+	// Game is already finished
+	ErrorCodeFinished ErrorCode = "finished"
+	// Action is made out of the player's turn
+	ErrorCodeNotYourTurn ErrorCode = "notYourTurn"
+	// Action is not available on the player's stage
+	ErrorCodeWrongStage ErrorCode = "wrongStage"
+	// Wrong number of pieces or combinations, or index out of range
+	ErrorCodeOutOfRange ErrorCode = "outOfRange"
+	// Piece is not in the player's hand or in the combination
+	ErrorCodeUnknownPiece ErrorCode = "unknownPiece"
+	// Piece is used more than once
+	ErrorCodeDuplicatePiece ErrorCode = "duplicatePiece"
+	// There is no combination with the given step number
+	ErrorCodeUnknownCombination ErrorCode = "unknownCombination"
+	// Combination breaks the rules
+	ErrorCodeInvalidCombination ErrorCode = "invalidCombination"
+	// Turn can't be committed
+	ErrorCodeInvalidTurn ErrorCode = "invalidTurn"
+	// Initial meld sum is less than the rule set requires
+	ErrorCodeInitialMeldSum ErrorCode = "initialMeldSum"
+	// Joker retrieval breaks the rules
+	ErrorCodeJokerRetrieval ErrorCode = "jokerRetrieval"
+	// Bank is empty
+	ErrorCodeBankEmpty ErrorCode = "bankEmpty"
+	// Bank is not empty
+	ErrorCodeBankNotEmpty ErrorCode = "bankNotEmpty"
+	// Player has no hints left
+	ErrorCodeHintLimit ErrorCode = "hintLimit"
+	// Game changed while the hint was computed
+	ErrorCodeStaleHint ErrorCode = "staleHint"
+)
+
+// Error with a code
+type Error struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Create error with the code and the formatted message
+func NewError(code ErrorCode, format string, a ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+// Code of the error
+//
+// Errors without a code get ErrorCodeRejected
+func ErrorCodeOf(err error) ErrorCode {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	var te *TurnError
+	if errors.As(err, &te) {
+		return ErrorCodeNotYourTurn
+	}
+
+	return ErrorCodeRejected
+}
+
+// Create error event
+func ErrorEvent(err error) *Event {
+	return NewEvent(EventTypeError, EventError{
+		Code:  ErrorCodeOf(err),
+		Error: err.Error(),
+	})
+}
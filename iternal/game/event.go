@@ -4,54 +4,158 @@
 
 package game
 
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/goccy/go-json"
+)
+
 // Game event
+//
+// Data is kept raw until the handler decodes it into the event's struct
 type Event struct {
-	Type EventType   `json:"type"`
-	Data interface{} `json:"data"`
+	Type EventType       `json:"type"`
+	Data json.RawMessage `json:"data"`
 }
 
 // Event error
 type EventError struct {
-	Error string `json:"error"`
+	Code  ErrorCode `json:"code"`
+	Error string    `json:"error"`
 }
 
-// Event InitialMeld
+// Create event with the encoded data
+func NewEvent(t EventType, data interface{}) *Event {
+	if data == nil {
+		return &Event{Type: t}
+	}
+
+	raw, _ := json.Marshal(data)
+	return &Event{Type: t, Data: raw}
+}
+
+// Decode event data into v
+//
+// Unknown fields are rejected, missing data leaves v unchanged
+func (e *Event) DecodeData(v interface{}) error {
+	return decodeEventData(e.Data, v)
+}
+
+// Error carried by the error event, nil for other events
+func (e *Event) Err() error {
+	if e.Type != EventTypeError {
+		return nil
+	}
+
+	var ee EventError
+	if err := e.DecodeData(&ee); err != nil {
+		return err
+	}
+
+	return NewError(ee.Code, "%v", ee.Error)
+}
+
+// Decode raw event data into v rejecting unknown fields
+func decodeEventData(data []byte, v interface{}) error {
+	if emptyEventData(data) {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return NewError(ErrorCodeInvalidData, "invalid event data: %v", err)
+	}
+
+	return nil
+}
+
+// Check if the event data is missing
+func emptyEventData(data []byte) bool {
+	return len(data) == 0 || string(data) == "null"
+}
+
+// Error of an action made out of the player's turn
+type TurnError struct {
+	Player  player
+	Current player
+}
+
+func (e *TurnError) Error() string {
+	return fmt.Sprintf(
+		"it's not %v's turn, current player is %v", e.Player, e.Current,
+	)
+}
+
+// Set the player who made the event, overwriting the given one
+func setEventPlayer(data []byte, p player) ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+
+	if !emptyEventData(data) {
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, NewError(
+				ErrorCodeInvalidData, "event data must be an object",
+			)
+		}
+	}
+
+	fields["player"], _ = json.Marshal(p)
+
+	return json.Marshal(fields)
+}
+
+// Event Init
+//
+// The token is secret and allows to reconnect to the same seat
 type EventInit struct {
 	Player string `json:"player"`
+	Token  string `json:"token"`
 }
 
 // Event InitialMeld
+//
+// Contains the ids of the pieces of every new combination
+// and the declared values of the jokers by their ids
 type EventInitialMeld struct {
-	Player      player `json:"player"`
-	AddedPieces []int  `json:"addedPieces"`
+	Player       player                     `json:"player"`
+	Combinations [][]string                 `json:"combinations"`
+	Jokers       map[string]JokerAssignment `json:"jokers,omitempty"`
 }
 
 // Event AddPiece
+//
+// Jokers contains the declared values of the jokers by their ids
 type EventAddPiece struct {
-	Player           player `json:"player"`
-	AddedPieces      []int  `json:"addedPieces"`
-	UsedCombinations []int  `json:"usedCombinations"`
+	Player           player                     `json:"player"`
+	AddedPieces      []string                   `json:"addedPieces"`
+	UsedCombinations []int                      `json:"usedCombinations"`
+	Jokers           map[string]JokerAssignment `json:"jokers,omitempty"`
 }
 
 // Event RemovePiece
 type EventRemovePiece struct {
 	Player           player `json:"player"`
-	RemovedPiece     int    `json:"removedPiece"`
+	RemovedPiece     string `json:"removedPiece"`
 	UsedCombinations []int  `json:"usedCombinations"`
 }
 
 // Event ReplacePiece
 type EventReplacePiece struct {
-	Player           player `json:"player"`
-	AddedPieces      []int  `json:"addedPieces"`
-	RemovedPiece     int    `json:"removedPiece"`
-	UsedCombinations []int  `json:"usedCombinations"`
+	Player           player   `json:"player"`
+	AddedPieces      []string `json:"addedPieces"`
+	RemovedPiece     string   `json:"removedPiece"`
+	UsedCombinations []int    `json:"usedCombinations"`
 }
 
 // Event AddCombination
+//
+// Jokers contains the declared values of the jokers by their ids
 type EventAddCombination struct {
-	Player      player `json:"player"`
-	AddedPieces []int  `json:"addedPieces"`
+	Player      player                     `json:"player"`
+	AddedPieces []string                   `json:"addedPieces"`
+	Jokers      map[string]JokerAssignment `json:"jokers,omitempty"`
 }
 
 // Event ConcatCombinations
@@ -67,6 +171,21 @@ type EventSplitCombination struct {
 	UsedCombinations []int  `json:"usedCombinations"`
 }
 
+// Event CommitTurn
+type EventCommitTurn struct {
+	Player player `json:"player"`
+}
+
+// Event RevertTurn
+type EventRevertTurn struct {
+	Player player `json:"player"`
+}
+
+// Event Draw
+type EventDraw struct {
+	Player player `json:"player"`
+}
+
 // Event Pass
 type EventPass struct {
 	Player player `json:"player"`
@@ -77,6 +196,55 @@ type EventReady struct {
 	Player string `json:"player"`
 }
 
+// Event Join
+type EventJoin struct {
+	Player string `json:"player"`
+}
+
+// Event Leave
+type EventLeave struct {
+	Player string `json:"player"`
+}
+
+// Event Timeout
+type EventTimeout struct {
+	Player player `json:"player"`
+}
+
+// Event Chat
+type EventChat struct {
+	Player  player `json:"player"`
+	Channel string `json:"channel"`
+	Text    string `json:"text,omitempty"`
+	Emote   string `json:"emote,omitempty"`
+}
+
+// Event Skip
+type EventSkip struct {
+	Player player `json:"player"`
+}
+
+// Event Phase
+//
+// Countdown is the number of seconds left before the start
+type EventPhase struct {
+	Phase     Phase `json:"phase"`
+	Countdown int   `json:"countdown"`
+}
+
+// Event Interpretations
+//
+// Pieces must be in the player's hand or on the field
+type EventInterpretations struct {
+	Player player   `json:"player"`
+	Pieces []string `json:"pieces"`
+}
+
+// Event Hint
+type EventHint struct {
+	Player player `json:"player"`
+}
+
 // Event type
 type EventType string
 
@@ -105,10 +273,34 @@ const (
 	EventTypeConcatCombinations EventType = "concatCombinations"
 	// Split the existing combination
 	EventTypeSplitCombination EventType = "splitCombination"
-	// Pass
+	// Validate the field and finish the turn
+	EventTypeCommitTurn EventType = "commitTurn"
+	// Revert all the changes made during the turn
+	EventTypeRevertTurn EventType = "revertTurn"
+	// Draw one piece from the bank
+	EventTypeDraw EventType = "draw"
+	// Pass without drawing (only if the bank is empty)
 	EventTypePass EventType = "pass"
 	// Ready to start
 	EventTypeReady EventType = "ready"
+	// Player added to the game (game log only)
+	EventTypeJoin EventType = "join"
+	// Player removed from the game (game log only)
+	EventTypeLeave EventType = "leave"
+	// Player exceeded the time limit (game log only)
+	EventTypeTimeout EventType = "timeout"
+	// Disconnected player's turn was skipped (game log only)
+	EventTypeSkip EventType = "skip"
+	// Chat message or emote
+	EventTypeChat EventType = "chat"
+	// Game was started (game log only)
+	EventTypeStart EventType = "start"
+	// Game moved to another phase
+	EventTypePhase EventType = "phase"
+	// List legal interpretations of the pieces
+	EventTypeInterpretations EventType = "interpretations"
+	// Suggest a play to the current player
+	EventTypeHint EventType = "hint"
 )
 
 // System events set
@@ -118,17 +310,30 @@ var systemEventsSet map[EventType]bool = map[EventType]bool{
 	EventTypeDisconnect: true,
 }
 
+// Events which finish the player's turn
+var turnEndingEventsSet map[EventType]bool = map[EventType]bool{
+	EventTypeInitialMeld: true,
+	EventTypeCommitTurn:  true,
+	EventTypeDraw:        true,
+	EventTypePass:        true,
+}
+
 // Initial events
-var initialEvents [3]EventType = [3]EventType{
+var initialEvents [1]EventType = [1]EventType{
 	EventTypeReady,
 }
 
 // Events available for initial meld stage
-var initialMeldEvents [2]EventType = [2]EventType{EventTypeInitialMeld, EventTypePass}
+var initialMeldEvents [1]EventType = [1]EventType{EventTypeInitialMeld}
 
 // Events available for main game stage
-var mainEvents [7]EventType = [7]EventType{
+var mainEvents [6]EventType = [6]EventType{
 	EventTypeAddPiece, EventTypeRemovePiece, EventTypeReplacePiece,
 	EventTypeAddCombination, EventTypeConcatCombinations,
-	EventTypeSplitCombination, EventTypePass,
+	EventTypeSplitCombination,
+}
+
+// Events available when the field was changed during the turn
+var changedFieldEvents [2]EventType = [2]EventType{
+	EventTypeCommitTurn, EventTypeRevertTurn,
 }
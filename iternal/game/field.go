@@ -4,7 +4,42 @@
 
 package game
 
+import "sort"
+
 // Field
 //
 // Maps steps with the combinations
 type field map[*step]*Combination
+
+// Copy field, so the copy's combinations can be changed independently
+func (f field) copy() field {
+	c := field{}
+
+	for s, comb := range f {
+		c[s] = comb.copy()
+	}
+
+	return c
+}
+
+// Field combinations ordered by their step numbers
+func (f field) ordered() []FieldCombination {
+	combinations := []FieldCombination{}
+
+	for s, comb := range f {
+		c := comb.copy()
+		combinations = append(combinations, FieldCombination{
+			ID:     s.number,
+			Owner:  s.player,
+			Type:   c.Type,
+			Pieces: c.Pieces,
+			Jokers: c.Jokers,
+		})
+	}
+
+	sort.Slice(combinations, func(i, j int) bool {
+		return combinations[i].ID < combinations[j].ID
+	})
+
+	return combinations
+}
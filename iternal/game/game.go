@@ -7,9 +7,7 @@ package game
 import (
 	"fmt"
 	"math/rand"
-	"sort"
-	"strconv"
-	"strings"
+	"time"
 
 	"github.com/goccy/go-json"
 )
@@ -18,6 +16,7 @@ import (
 //
 // Handles players' actions, provides game logic and tracks history
 type Game struct {
+	rules        RuleSet
 	field        field
 	history      *history
 	bank         pack
@@ -27,50 +26,92 @@ type Game struct {
 	turn         int
 	players      []player
 	readyPlayers map[player]bool
-	finished     bool
+	phase        Phase
+	countdown    time.Time
 	winner       player
-	started      bool
+	scores       map[player]int
+	passes       int
+	workspace    *workspace
+	hints        map[player]int
+	version      int
+	clock        Clock
+	rand         *rand.Rand
 }
 
 // Create new game
-func NewGame() *Game {
-	return &Game{
-		field:      field{},
-		history:    createHistory(),
-		bank:       createInitialPack(),
-		hands:      map[player]hand{},
-		stages:     map[player]stage{},
-		stepNumber: 1,
-		players:    []player{},
-		finished:   false,
-		started:    false,
+//
+// The seed defines the order of the pieces in the bank,
+// so games with the same seed and events are identical
+func NewGame(rules RuleSet, seed int64) (*Game, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
 	}
+
+	return &Game{
+		rules:        rules,
+		field:        field{},
+		history:      createHistory(seed),
+		bank:         createInitialPack(&rules),
+		hands:        map[player]hand{},
+		stages:       map[player]stage{},
+		scores:       map[player]int{},
+		stepNumber:   1,
+		players:      []player{},
+		readyPlayers: map[player]bool{},
+		phase:        PhaseOpen,
+		hints:        map[player]int{},
+		clock:        systemClock{},
+		rand:         rand.New(rand.NewSource(seed)),
+	}, nil
+}
+
+// Replace the clock used by the turn timer
+func (g *Game) SetClock(c Clock) {
+	g.clock = c
 }
 
 // Add player
+//
+// Players can join until the game is started
 func (g *Game) AddPlayer(p string) *Event {
-	if len(g.players) > MaxPlayersNumber || len(g.players) < MinPlayersNumber {
-		return &Event{
-			EventTypeError,
-			EventError{fmt.Sprintf(
-				"must be from %v to %v players", MinPlayersNumber, MaxPlayersNumber,
-			)},
-		}
+	if !g.phase.seating() {
+		return ErrorEvent(NewError(
+			ErrorCodeWrongPhase, "players can't join the game in phase %v", g.phase,
+		))
+	}
+
+	if len(g.players) >= g.rules.MaxPlayersNumber {
+		return ErrorEvent(NewError(
+			ErrorCodePlayersNumber,
+			"must be from %v to %v players",
+			g.rules.MinPlayersNumber, g.rules.MaxPlayersNumber,
+		))
 	}
 
 	player_ := player(p)
+	if _, ok := g.readyPlayers[player_]; ok {
+		return ErrorEvent(NewError(
+			ErrorCodeUnknownPlayer, "player %v is already in the game", p,
+		))
+	}
+
 	g.players = append(g.players, player_)
 	g.readyPlayers[player_] = false
 	g.hands[player_] = hand{}
 	g.stages[player_] = systemStage
 
+	g.record(player_, EventTypeJoin, EventJoin{Player: p})
+
+	g.updateSeating()
+
 	return &Event{Type: EventTypeSuccess}
 }
 
 // Remove player
 func (g *Game) RemovePlayer(p string) error {
 	if len(g.players) <= 0 {
-		return fmt.Errorf(
+		return NewError(
+			ErrorCodePlayersNumber,
 			"at least one player should be in the game to remove him",
 		)
 	}
@@ -85,67 +126,124 @@ func (g *Game) RemovePlayer(p string) error {
 	}
 
 	if playerIndex == -1 {
-		return fmt.Errorf("no player with name %v", p)
+		return NewError(ErrorCodeUnknownPlayer, "no player with name %v", p)
 	}
 
-	g.players = append(g.players[:playerIndex], g.players[playerIndex+1:]...)
+	// Finished games keep the last turn as it was committed
+	started := g.phase == PhaseStarted
+
+	if started {
+		if playerIndex == g.turn {
+			g.revertTurn()
+		}
 
-	if g.started {
 		g.bank = append(g.bank, g.hands[player_]...)
+	}
 
-		if playerIndex == g.turn {
-			g.nextPlayer()
+	g.players = append(g.players[:playerIndex], g.players[playerIndex+1:]...)
+
+	if started && len(g.players) > 0 {
+		if playerIndex < g.turn {
+			g.turn -= 1
+		} else if playerIndex == g.turn {
+			if g.turn == len(g.players) {
+				g.turn = 0
+			}
+			g.beginTurn()
 		}
 	}
 
 	delete(g.hands, player_)
 	delete(g.stages, player_)
+	delete(g.readyPlayers, player_)
+
+	g.record(player_, EventTypeLeave, EventLeave{Player: p})
+
+	g.updateSeating()
 
 	return nil
 }
 
-// Try to start the game
-func (g *Game) tryStart() {
-	for _, r := range g.readyPlayers {
-		if !r {
-			return
-		}
+// Start game
+//
+// Allowed when enough players joined, even if some of them
+// are not ready yet
+func (g *Game) Start() error {
+	if g.phase == PhaseOpen {
+		return NewError(
+			ErrorCodePlayersNumber,
+			"at least %v players are needed to start the game",
+			g.rules.MinPlayersNumber,
+		)
+	}
+
+	if g.phase != PhaseReadyCheck && g.phase != PhaseCountdown {
+		return NewError(
+			ErrorCodeWrongPhase, "game can't be started in phase %v", g.phase,
+		)
+	}
+
+	if err := g.setPhase(PhaseStarted); err != nil {
+		return err
 	}
-	g.Start()
-}
 
-// Start game
-func (g *Game) Start() {
 	g.shuffleBank()
 	g.firstPick()
 	g.turnQueue()
-	g.started = true
+
+	for p := range g.stages {
+		g.stages[p] = initialMeldStage
+	}
+
+	g.beginTurn()
+
+	g.record("", EventTypeStart, nil)
+
+	return nil
 }
 
-// Start game
+// Check if game is started (it stays started after the finish)
 func (g *Game) IsStarted() bool {
-	return g.started
+	return !g.phase.seating()
+}
+
+// Check if game is finished
+func (g *Game) IsFinished() bool {
+	return g.phase == PhaseFinished
+}
+
+// Seed of the game's random source
+func (g *Game) Seed() int64 {
+	return g.history.seed
+}
+
+// Game rule set
+func (g *Game) Rules() RuleSet {
+	return g.rules
 }
 
 // Randomly shuffle bank
 func (g *Game) shuffleBank() {
 	for i := range g.bank {
-		j := rand.Intn(i + 1)
+		j := g.rand.Intn(i + 1)
 		g.bank[i], g.bank[j] = g.bank[j], g.bank[i]
 	}
 }
 
 // Deal pieces to players
 func (g *Game) firstPick() {
-	for p := range g.hands {
-		g.hands[p] = hand(g.bank[:HandSize])
-		g.bank = g.bank[HandSize:]
+	for _, p := range g.players {
+		g.hands[p] = append(hand{}, g.bank[:g.rules.HandSize]...)
+		g.bank = g.bank[g.rules.HandSize:]
 	}
 }
 
 // Create turn queue
+//
+// The player with the largest piece goes first, the first
+// seat does if there are only jokers in the hands
 func (g *Game) turnQueue() {
-	firstPlayerIndex := -1
+	firstPlayerIndex := 0
 	firstPlayerValue := MinNumber - 1
 	if firstPlayerValue > JokerNumber {
 		firstPlayerValue = JokerNumber
@@ -170,103 +268,286 @@ func (g *Game) State(p string) *State {
 		return &State{Error: fmt.Sprintf("there is no player with id %v", p)}
 	}
 
+	return g.state(player_, false)
+}
+
+// Game state for spectators
+//
+// Contains no hands unless reveal is true or the game is finished
+func (g *Game) SpectatorState(reveal bool) *State {
+	return g.state("", reveal)
+}
+
+// Game state as seen by the player (spectator if the player is empty)
+//
+// The state is a copy, it doesn't change with the game
+func (g *Game) state(player_ player, reveal bool) *State {
+	spectator := player_ == ""
+
 	turn := false
-	if g.started {
-		turn = g.players[g.turn] == player_
+	if !spectator {
+		turn = player(g.CurrentPlayer()) == player_
+	}
+
+	timeLeft := 0
+	if g.phase == PhaseStarted {
+		timeLeft = g.workspace.timeLeft(g.clock.Now())
+	}
+
+	availableEvents := []EventType{}
+	if !spectator {
+		availableEvents = g.stages[player_].availableEvents(
+			turn, turn && g.workspace.changed(), len(g.bank) == 0,
+		)
+	}
+
+	hintsLeft := 0
+	if !spectator {
+		hintsLeft = g.hintsLeft(player_)
+	}
+
+	var seed *int64
+	if g.IsFinished() {
+		s := g.history.seed
+		seed = &s
+	}
+
+	opponents := []Opponent{}
+	for _, p := range g.players {
+		if p != player_ {
+			opponents = append(opponents, Opponent{p, len(g.hands[p])})
+		}
+	}
+
+	var hands map[player]hand
+	if reveal || g.IsFinished() {
+		hands = map[player]hand{}
+		for _, p := range g.players {
+			hands[p] = append(hand{}, g.hands[p]...)
+		}
+	}
+
+	scores := map[player]int{}
+	for p, s := range g.scores {
+		scores[p] = s
 	}
 
 	return &State{
-		Turn: turn,
-		// Field:           g.field,
-		Hand:            g.hands[player_],
-		AvailableEvents: g.stages[player_].availableEvents(),
-		Started:         g.started,
-		Finished:        g.finished,
+		Turn:            turn,
+		Field:           g.field.ordered(),
+		BankSize:        len(g.bank),
+		Opponents:       opponents,
+		Hand:            append(hand{}, g.hands[player_]...),
+		Hands:           hands,
+		AvailableEvents: availableEvents,
+		TimeLeft:        timeLeft,
+		Phase:           g.phase,
+		Countdown:       g.CountdownLeft(),
+		Started:         g.IsStarted(),
+		Finished:        g.IsFinished(),
 		Winner:          g.winner,
+		Scores:          scores,
+		HintsLeft:       hintsLeft,
+		Rules:           g.rules,
+		Seed:            seed,
 		Error:           "",
 	}
 }
 
 // Events handler
-func (g *Game) HandleEvent(e *Event) *Event {
+//
+// The event is made by the player p, the player given
+// in the event data is ignored
+func (g *Game) HandleEvent(p string, e *Event) *Event {
 	// Handle system events
 	if _, ok := systemEventsSet[e.Type]; ok {
 		return &Event{
 			Type: EventTypeSuccess,
 		}
 	}
+	// Handle queries
+	if e.Type == EventTypeInterpretations {
+		return g.interpretationsHandle(player(p), e.Data)
+	}
+	// Hints are computed by a hinter outside of the game
+	if e.Type == EventTypeHint {
+		return ErrorEvent(NewError(
+			ErrorCodeUnknownEvent, "hint is answered with QueryHint and AnswerHint",
+		))
+	}
 	// Handle action
-	err := g.handleAction(e)
+	err := g.handleAction(player(p), e)
 	if err == nil {
 		return &Event{
 			Type: EventTypeSuccess,
 		}
 	}
-	return &Event{
-		Type: EventTypeError,
-		Data: EventError{
-			Error: err.Error(),
-		},
-	}
+	return ErrorEvent(err)
 }
 
 // Handle player's action
-func (g *Game) handleAction(e *Event) error {
-	data, err := json.Marshal(e.Data)
+func (g *Game) handleAction(player_ player, e *Event) error {
+	data, err := setEventPlayer(e.Data, player_)
 	if err != nil {
 		return err
 	}
 
 	if e.Type == EventTypeReady {
-		return g.readyHandle(data)
+		err = g.readyHandle(data)
+		if err == nil {
+			g.record(player_, e.Type, json.RawMessage(data))
+		}
+		return err
 	}
 
-	err = fmt.Errorf("there is no event: %v", e.Type)
+	handle := g.actionHandler(e.Type)
+	if handle == nil {
+		return NewError(ErrorCodeUnknownEvent, "there is no event: %v", e.Type)
+	}
 
-	if !g.started {
-		return fmt.Errorf("game is not started yet")
+	if !g.IsStarted() {
+		return NewError(ErrorCodeNotStarted, "game is not started yet")
 	}
 
-	switch e.Type {
+	if g.phase != PhaseStarted {
+		return NewError(ErrorCodeFinished, "game is already %v", g.phase)
+	}
+
+	if current := player(g.CurrentPlayer()); current != player_ {
+		return &TurnError{Player: player_, Current: current}
+	}
+
+	err = handle(data)
+
+	if err == nil {
+		if turnEndingEventsSet[e.Type] {
+			g.finishTurn(e.Type == EventTypePass)
+		}
+
+		g.record(player_, e.Type, json.RawMessage(data))
+	}
+
+	return err
+}
+
+// Handler of the player's action, nil if there is no such action
+func (g *Game) actionHandler(t EventType) func(data []byte) error {
+	switch t {
 	case EventTypeInitialMeld:
-		err = g.initialMeldHandle(data)
+		return g.initialMeldHandle
 	case EventTypeAddPiece:
-		err = g.addPieceHandle(data)
+		return g.addPieceHandle
 	case EventTypeRemovePiece:
-		err = g.removePieceHandle(data)
+		return g.removePieceHandle
 	case EventTypeReplacePiece:
-		err = g.replacePieceHandle(data)
+		return g.replacePieceHandle
 	case EventTypeAddCombination:
-		err = g.addCombinationHandle(data)
+		return g.addCombinationHandle
 	case EventTypeConcatCombinations:
-		err = g.concatCombinations(data)
+		return g.concatCombinations
 	case EventTypeSplitCombination:
-		err = g.splitCombination(data)
+		return g.splitCombination
+	case EventTypeCommitTurn:
+		return g.commitTurnHandle
+	case EventTypeRevertTurn:
+		return g.revertTurnHandle
+	case EventTypeDraw:
+		return g.drawHandle
 	case EventTypePass:
-		err = g.passHandle(data)
+		return g.passHandle
 	}
+	return nil
+}
 
-	if err == nil {
-		// Check if game is finished
-		if !g.gameFinished() {
-			g.nextPlayer()
-		}
+// Finish the current player's turn if its time limit is exceeded
+//
+// Uncommitted changes are reverted and the player gets penalty pieces.
+// Returns true if the turn was finished
+func (g *Game) CheckTimeout() bool {
+	if g.CurrentPlayer() == "" {
+		return false
 	}
 
-	return err
+	if g.workspace.timeLeft(g.clock.Now()) > 0 {
+		return false
+	}
+
+	g.timeout()
+
+	return true
+}
+
+// Player whose turn it is, empty if the game is not in progress
+func (g *Game) CurrentPlayer() string {
+	if g.phase != PhaseStarted || g.turn >= len(g.players) {
+		return ""
+	}
+
+	return string(g.players[g.turn])
+}
+
+// Skip the current player's turn
+//
+// Uncommitted changes are reverted, the player doesn't draw.
+// Used when the player is disconnected
+func (g *Game) SkipTurn() error {
+	if g.CurrentPlayer() == "" {
+		return NewError(ErrorCodeNotStarted, "there is no turn to skip")
+	}
+
+	player_ := g.players[g.turn]
+
+	g.revertTurn()
+	g.finishTurn(true)
+
+	g.record(player_, EventTypeSkip, EventSkip{Player: player_})
+
+	return nil
+}
+
+// Penalize the current player for exceeding the time limit
+func (g *Game) timeout() {
+	player_ := g.players[g.turn]
+	passed := len(g.bank) == 0
+
+	g.penalize(player_)
+	g.finishTurn(passed)
+
+	g.record(player_, EventTypeTimeout, EventTimeout{Player: player_})
+}
+
+// Finish the current player's turn and pass it to the next player
+func (g *Game) finishTurn(passed bool) {
+	if passed {
+		g.passes += 1
+	} else {
+		g.passes = 0
+	}
+
+	// Check if game is finished
+	if !g.gameFinished() {
+		g.nextPlayer()
+	}
 }
 
 // Check if game is finished
+//
+// The game is finished when the current player's hand is empty or
+// when the bank is empty and every player passed during a full round
 func (g *Game) gameFinished() bool {
 	player_ := g.players[g.turn]
-	finished := len(g.hands[player_]) == 0
 
-	if finished {
-		g.finished = true
-		g.winner = g.players[g.turn]
+	if len(g.hands[player_]) == 0 {
+		g.finish(player_)
+		return true
 	}
 
-	return finished
+	if len(g.bank) == 0 && g.passes >= len(g.players) {
+		g.finish(g.lowestHandPlayer())
+		return true
+	}
+
+	return false
 }
 
 // Next player
@@ -276,42 +557,83 @@ func (g *Game) nextPlayer() {
 	if g.turn == len(g.players) {
 		g.turn = 0
 	}
+
+	g.beginTurn()
 }
 
-// Add penalty pieces to the player's hand
+// Pass the turn without drawing (allowed only if the bank is empty)
 func (g *Game) passHandle(data []byte) error {
 	var e EventPass
-	json.Unmarshal(data, &e)
+	if err := decodeEventData(data, &e); err != nil {
+		return err
+	}
 
-	bankLen := len(g.bank)
+	if len(g.bank) != 0 {
+		return NewError(
+			ErrorCodeBankNotEmpty, "can't pass while the bank is not empty",
+		)
+	}
 
-	var slicePos int
-	if bankLen == 0 {
-		return nil
-	} else if bankLen >= PenaltySize {
-		slicePos = PenaltySize
-	} else {
-		slicePos = bankLen
+	g.revertTurn()
+
+	return nil
+}
+
+// Draw one piece from the bank
+func (g *Game) drawHandle(data []byte) error {
+	var e EventDraw
+	if err := decodeEventData(data, &e); err != nil {
+		return err
 	}
 
-	g.hands[e.Player] = append(g.hands[e.Player], g.bank[:slicePos]...)
-	g.bank = g.bank[slicePos:]
+	if len(g.bank) == 0 {
+		return NewError(ErrorCodeBankEmpty, "the bank is empty")
+	}
+
+	g.revertTurn()
+	g.drawPieces(e.Player, 1)
 
 	return nil
 }
 
+// Revert player's turn and add penalty pieces to the player's hand
+func (g *Game) penalize(player_ player) {
+	g.revertTurn()
+	g.drawPieces(player_, g.rules.PenaltySize)
+}
+
+// Move pieces from the bank to the player's hand
+func (g *Game) drawPieces(player_ player, number int) {
+	if number > len(g.bank) {
+		number = len(g.bank)
+	}
+
+	g.hands[player_] = append(g.hands[player_], g.bank[:number]...)
+	g.bank = g.bank[number:]
+}
+
 // Add penalty pieces to the player's hand
 func (g *Game) readyHandle(data []byte) error {
 	var e EventReady
-	json.Unmarshal(data, &e)
+	if err := decodeEventData(data, &e); err != nil {
+		return err
+	}
+
+	if !g.phase.seating() {
+		return NewError(
+			ErrorCodeWrongPhase, "players can't get ready in phase %v", g.phase,
+		)
+	}
 
 	if _, ok := g.readyPlayers[player(e.Player)]; !ok {
-		return fmt.Errorf("there is no player with name: %v", e.Player)
+		return NewError(
+			ErrorCodeUnknownPlayer, "there is no player with name: %v", e.Player,
+		)
 	}
 
 	g.readyPlayers[player(e.Player)] = true
 
-	g.tryStart()
+	g.updateSeating()
 
 	return nil
 }
@@ -319,43 +641,114 @@ func (g *Game) readyHandle(data []byte) error {
 // Initial meld action handler
 func (g *Game) initialMeldHandle(data []byte) error {
 	var e EventInitialMeld
-	json.Unmarshal(data, &e)
+	if err := decodeEventData(data, &e); err != nil {
+		return err
+	}
 
 	if g.stages[e.Player] != initialMeldStage {
-		return fmt.Errorf("wrong game stage for player: %v", e.Player)
+		return NewError(
+			ErrorCodeWrongStage, "wrong game stage for player: %v", e.Player,
+		)
 	}
 
-	pieces, notFoundIndex := g.gatherPieces(e.Player, e.AddedPieces)
-	if pieces == nil {
-		return fmt.Errorf("there is no piece with index %v", notFoundIndex)
+	if len(e.Combinations) == 0 {
+		return NewError(
+			ErrorCodeOutOfRange, "at least one combination must be added",
+		)
 	}
 
-	combination := validInitialMeld(pieces)
-	if combination == nil {
-		return fmt.Errorf("invalid combination")
+	ids := []string{}
+	for _, c := range e.Combinations {
+		ids = append(ids, c...)
 	}
 
-	g.placeCombination(e.Player, combination)
-	g.removePiecesFromHand(e.Player, e.AddedPieces)
+	// Check that every piece is in the hand and is used once
+	allPieces, err := g.gatherPieces(e.Player, ids)
+	if err != nil {
+		return err
+	}
+
+	if err := validDeclarations(allPieces, e.Jokers); err != nil {
+		return err
+	}
+
+	pieces := [][]*Piece{}
+	for _, c := range e.Combinations {
+		p, _ := g.gatherPieces(e.Player, c)
+		pieces = append(pieces, p)
+	}
+
+	combinations, err := g.rules.validInitialMeld(pieces, e.Jokers)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range combinations {
+		g.placeCombination(e.Player, c)
+	}
+	g.removePiecesFromHand(e.Player, ids)
 	g.stages[e.Player] = mainGameStage
 	return nil
 }
 
+// Commit turn action handler
+func (g *Game) commitTurnHandle(data []byte) error {
+	var e EventCommitTurn
+	if err := decodeEventData(data, &e); err != nil {
+		return err
+	}
+
+	if g.workspace.player != e.Player {
+		return NewError(
+			ErrorCodeNotYourTurn,
+			"there is no turn to commit for player: %v", e.Player,
+		)
+	}
+
+	return g.commitTurn()
+}
+
+// Revert turn action handler
+func (g *Game) revertTurnHandle(data []byte) error {
+	var e EventRevertTurn
+	if err := decodeEventData(data, &e); err != nil {
+		return err
+	}
+
+	if g.workspace.player != e.Player {
+		return NewError(
+			ErrorCodeNotYourTurn,
+			"there is no turn to revert for player: %v", e.Player,
+		)
+	}
+
+	g.revertTurn()
+
+	return nil
+}
+
 // Add piece handler
 func (g *Game) addPieceHandle(data []byte) error {
 	var e EventAddPiece
-	json.Unmarshal(data, &e)
+	if err := decodeEventData(data, &e); err != nil {
+		return err
+	}
 
 	if g.stages[e.Player] == initialMeldStage {
-		return fmt.Errorf("wrong stage action for player: %v", e.Player)
+		return NewError(
+			ErrorCodeWrongStage, "wrong stage action for player: %v", e.Player,
+		)
 	}
 
 	if len(e.AddedPieces) != 1 {
-		return fmt.Errorf("exactly one piece per action can be added")
+		return NewError(
+			ErrorCodeOutOfRange, "exactly one piece per action can be added",
+		)
 	}
 
 	if len(e.UsedCombinations) != 1 {
-		return fmt.Errorf(
+		return NewError(
+			ErrorCodeOutOfRange,
 			"excatly one combination per action can be used for addition",
 		)
 	}
@@ -363,34 +756,33 @@ func (g *Game) addPieceHandle(data []byte) error {
 	stepNumber := e.UsedCombinations[0]
 	combination := g.combinationByStepNumber(stepNumber)
 	if combination == nil {
-		return fmt.Errorf("there is no combination with index %v", stepNumber)
+		return NewError(
+			ErrorCodeUnknownCombination,
+			"there is no combination with index %v", stepNumber,
+		)
 	}
 
-	var pieces pack
-	var pieceIndex int
-	var piece *Piece
-
-	pieceIndex = e.AddedPieces[0]
-	piece = g.pieceByIndex(e.Player, pieceIndex)
+	pieceID := e.AddedPieces[0]
+	piece := g.pieceByID(e.Player, pieceID)
 	if piece == nil {
-		return fmt.Errorf(
-			"there is no piece with index %v", pieceIndex,
+		return NewError(
+			ErrorCodeUnknownPiece,
+			"player %v doesn't own piece %v", e.Player, pieceID,
 		)
 	}
 
-	pieces = append(combination.Pieces, piece)
+	pieces := append(pack{}, combination.Pieces...)
+	pieces = append(pieces, piece)
 
-	newCombination := validCombination(pieces)
-	if newCombination == nil {
-		return fmt.Errorf(
-			"can't add the piece %v to the combination %v",
-			pieceIndex, stepNumber,
-		)
+	if err := validDeclarations(pieces, e.Jokers); err != nil {
+		return err
 	}
 
-	g.placeCombination(e.Player, newCombination)
+	declared := mergeDeclarations(combination.declarations(), e.Jokers)
+
+	g.placeCombination(e.Player, draftCombination(pieces, declared))
 	g.deleteCombinationByStepNumber(stepNumber)
-	g.removePieceFromHand(e.Player, pieceIndex)
+	g.removePieceFromHand(e.Player, pieceID)
 
 	return nil
 }
@@ -398,14 +790,19 @@ func (g *Game) addPieceHandle(data []byte) error {
 // Remove piece handler
 func (g *Game) removePieceHandle(data []byte) error {
 	var e EventRemovePiece
-	json.Unmarshal(data, &e)
+	if err := decodeEventData(data, &e); err != nil {
+		return err
+	}
 
 	if g.stages[e.Player] == initialMeldStage {
-		return fmt.Errorf("wrong stage action for player: %v", e.Player)
+		return NewError(
+			ErrorCodeWrongStage, "wrong stage action for player: %v", e.Player,
+		)
 	}
 
 	if len(e.UsedCombinations) != 1 {
-		return fmt.Errorf(
+		return NewError(
+			ErrorCodeOutOfRange,
 			"excatly one combination per action can be used for removing",
 		)
 	}
@@ -413,30 +810,40 @@ func (g *Game) removePieceHandle(data []byte) error {
 	stepNumber := e.UsedCombinations[0]
 	combination := g.combinationByStepNumber(stepNumber)
 	if combination == nil {
-		return fmt.Errorf("there is no combination with index %v", stepNumber)
+		return NewError(
+			ErrorCodeUnknownCombination,
+			"there is no combination with index %v", stepNumber,
+		)
 	}
 
-	var pieces pack
-	var pieceIndex int
-	var piece *Piece
-
-	pieceIndex = e.RemovedPiece
-	piece = combination.Pieces[pieceIndex]
+	pieceIndex := combination.Pieces.indexByID(e.RemovedPiece)
+	if pieceIndex == -1 {
+		return NewError(
+			ErrorCodeUnknownPiece,
+			"there is no piece %v in combination %v",
+			e.RemovedPiece, stepNumber,
+		)
+	}
 
-	pieces = append(
-		combination.Pieces[:pieceIndex],
-		combination.Pieces[pieceIndex+1:]...,
-	)
+	piece := combination.Pieces[pieceIndex]
 
-	newCombination := validCombination(pieces)
-	if newCombination == nil {
-		return fmt.Errorf(
-			"can't remove the piece %v to the combination %v",
-			pieceIndex, stepNumber,
+	// With the strict rule jokers of the field are retrieved only by replacing
+	if value, ok := g.workspace.jokers[piece.ID]; ok && piece.Joker && g.rules.StrictJokerRetrieval {
+		return NewError(
+			ErrorCodeJokerRetrieval,
+			"joker %v can be retrieved only by %v %v",
+			piece.ID, value.Color, value.Number,
 		)
 	}
 
-	g.placeCombination(e.Player, newCombination)
+	pieces := append(pack{}, combination.Pieces[:pieceIndex]...)
+	pieces = append(pieces, combination.Pieces[pieceIndex+1:]...)
+
+	if len(pieces) > 0 {
+		g.placeCombination(
+			e.Player, draftCombination(pieces, combination.declarations()),
+		)
+	}
 	g.deleteCombinationByStepNumber(stepNumber)
 	g.addPieceToHand(e.Player, piece)
 
@@ -446,18 +853,25 @@ func (g *Game) removePieceHandle(data []byte) error {
 // Repalce action handler
 func (g *Game) replacePieceHandle(data []byte) error {
 	var e EventReplacePiece
-	json.Unmarshal(data, &e)
+	if err := decodeEventData(data, &e); err != nil {
+		return err
+	}
 
 	if g.stages[e.Player] == initialMeldStage {
-		return fmt.Errorf("wrong stage action for player: %v", e.Player)
+		return NewError(
+			ErrorCodeWrongStage, "wrong stage action for player: %v", e.Player,
+		)
 	}
 
 	if len(e.AddedPieces) != 1 {
-		return fmt.Errorf("exactly one piece per action can be replaced")
+		return NewError(
+			ErrorCodeOutOfRange, "exactly one piece per action can be replaced",
+		)
 	}
 
 	if len(e.UsedCombinations) != 1 {
-		return fmt.Errorf(
+		return NewError(
+			ErrorCodeOutOfRange,
 			"excatly one combination per action can be used for replacing",
 		)
 	}
@@ -465,31 +879,74 @@ func (g *Game) replacePieceHandle(data []byte) error {
 	stepNumber := e.UsedCombinations[0]
 	combination := g.combinationByStepNumber(stepNumber)
 	if combination == nil {
-		return fmt.Errorf("there is no combination with index %v", stepNumber)
+		return NewError(
+			ErrorCodeUnknownCombination,
+			"there is no combination with index %v", stepNumber,
+		)
 	}
 
-	toAddPieceIndex := e.AddedPieces[0]
-	toRemovePieceIndex := e.RemovedPiece
-
-	toAddPiece := g.pieceByIndex(e.Player, toAddPieceIndex)
-	pieceToRemove := combination.Pieces[toRemovePieceIndex]
+	toAddPieceID := e.AddedPieces[0]
 
-	pieces := combination.Pieces[:]
-	pieces[toRemovePieceIndex] = toAddPiece
+	toAddPiece := g.pieceByID(e.Player, toAddPieceID)
+	if toAddPiece == nil {
+		return NewError(
+			ErrorCodeUnknownPiece,
+			"player %v doesn't own piece %v", e.Player, toAddPieceID,
+		)
+	}
 
-	newCombination := validCombination(pieces)
-	if newCombination == nil {
-		return fmt.Errorf(
-			"piece %v from hand can't replace piece %v from combination %v",
-			toAddPieceIndex, toRemovePieceIndex, stepNumber,
+	toRemovePieceIndex := combination.Pieces.indexByID(e.RemovedPiece)
+	if toRemovePieceIndex == -1 {
+		return NewError(
+			ErrorCodeUnknownPiece,
+			"there is no piece %v in combination %v",
+			e.RemovedPiece, stepNumber,
 		)
 	}
 
-	g.placeCombination(e.Player, newCombination)
+	pieceToRemove := combination.Pieces[toRemovePieceIndex]
+
+	if err := g.retrieveJoker(pieceToRemove, toAddPiece); err != nil {
+		return err
+	}
+
+	pieces := append(pack{}, combination.Pieces...)
+	pieces[toRemovePieceIndex] = toAddPiece
+
+	g.placeCombination(
+		e.Player, draftCombination(pieces, combination.declarations()),
+	)
 	g.deleteCombinationByStepNumber(stepNumber)
-	g.removePieceFromHand(e.Player, toAddPieceIndex)
+	g.removePieceFromHand(e.Player, toAddPieceID)
 	g.addPieceToHand(e.Player, pieceToRemove)
-	pieceToRemove.clearIfJoker()
+
+	return nil
+}
+
+// Check joker retrieval from the field and track the retrieved joker
+//
+// With the strict rule the joker must be replaced by the piece it
+// represented at the beginning of the turn
+func (g *Game) retrieveJoker(joker *Piece, replacement *Piece) error {
+	ws := g.workspace
+
+	value, ok := ws.jokers[joker.ID]
+	if !joker.Joker || !ok {
+		return nil
+	}
+
+	if g.rules.StrictJokerRetrieval {
+		if replacement.Joker || replacement.Number != value.Number ||
+			replacement.Color != value.Color {
+			return NewError(
+				ErrorCodeJokerRetrieval,
+				"joker %v can be retrieved only by %v %v",
+				joker.ID, value.Color, value.Number,
+			)
+		}
+	}
+
+	ws.retrieved[joker] = true
 
 	return nil
 }
@@ -497,70 +954,80 @@ func (g *Game) replacePieceHandle(data []byte) error {
 // Add combination action handler
 func (g *Game) addCombinationHandle(data []byte) error {
 	var e EventAddCombination
-	json.Unmarshal(data, &e)
+	if err := decodeEventData(data, &e); err != nil {
+		return err
+	}
 
 	if g.stages[e.Player] == initialMeldStage {
-		return fmt.Errorf("wrong game stage for player: %v", e.Player)
+		return NewError(
+			ErrorCodeWrongStage, "wrong game stage for player: %v", e.Player,
+		)
 	}
 
-	pieces, notFoundIndex := g.gatherPieces(e.Player, e.AddedPieces)
-	if pieces == nil {
-		return fmt.Errorf("there is no piece with index %v", notFoundIndex)
+	if len(e.AddedPieces) == 0 {
+		return NewError(ErrorCodeOutOfRange, "at least one piece must be added")
 	}
 
-	newCombination := validCombination(pieces)
+	pieces, err := g.gatherPieces(e.Player, e.AddedPieces)
+	if err != nil {
+		return err
+	}
 
-	if newCombination != nil {
-		g.placeCombination(e.Player, newCombination)
-		g.removePiecesFromHand(e.Player, e.AddedPieces)
-		return nil
+	if err := validDeclarations(pieces, e.Jokers); err != nil {
+		return err
 	}
 
-	return fmt.Errorf("invalid combination")
+	g.placeCombination(e.Player, draftCombination(pieces, e.Jokers))
+	g.removePiecesFromHand(e.Player, e.AddedPieces)
+
+	return nil
 }
 
 // Concat combinations action handler
 func (g *Game) concatCombinations(data []byte) error {
-	var e EventAddPiece
-	json.Unmarshal(data, &e)
+	var e EventConcatCombinations
+	if err := decodeEventData(data, &e); err != nil {
+		return err
+	}
 
 	if g.stages[e.Player] == initialMeldStage {
-		return fmt.Errorf("wrong stage action for player: %v", e.Player)
+		return NewError(
+			ErrorCodeWrongStage, "wrong stage action for player: %v", e.Player,
+		)
 	}
 
 	if len(e.UsedCombinations) < 2 {
-		return fmt.Errorf("at leat 2 combination can be concatenated")
+		return NewError(
+			ErrorCodeOutOfRange, "at leat 2 combination can be concatenated",
+		)
 	}
 
-	pieces := []*Piece{}
+	pieces := pack{}
+	declared := map[string]JokerAssignment{}
+	used := map[int]bool{}
 
 	for _, stepNumber := range e.UsedCombinations {
+		if used[stepNumber] {
+			return NewError(
+				ErrorCodeOutOfRange,
+				"combination %v can't be concatenated with itself", stepNumber,
+			)
+		}
+		used[stepNumber] = true
+
 		combination := g.combinationByStepNumber(stepNumber)
 		if combination == nil {
-			return fmt.Errorf(
+			return NewError(
+				ErrorCodeUnknownCombination,
 				"there is no combination with index %v", stepNumber,
 			)
 		}
 
-		for _, p := range combination.Pieces {
-			pieces = append(pieces, p)
-		}
-	}
-
-	newCombination := validCombination(pieces)
-	if newCombination == nil {
-		stepStrings := []string{}
-		for _, stepNumber := range e.UsedCombinations {
-			stepStrings = append(stepStrings, strconv.Itoa(stepNumber))
-		}
-
-		return fmt.Errorf(
-			"combinations [%v] can't be concatenated to the valid one",
-			strings.Join(stepStrings, ", "),
-		)
+		pieces = append(pieces, combination.Pieces...)
+		declared = mergeDeclarations(declared, combination.declarations())
 	}
 
-	g.placeCombination(e.Player, newCombination)
+	g.placeCombination(e.Player, draftCombination(pieces, declared))
 
 	for _, stepNumber := range e.UsedCombinations {
 		g.deleteCombinationByStepNumber(stepNumber)
@@ -572,50 +1039,142 @@ func (g *Game) concatCombinations(data []byte) error {
 // Split combination action handler
 func (g *Game) splitCombination(data []byte) error {
 	var e EventSplitCombination
-	json.Unmarshal(data, &e)
+	if err := decodeEventData(data, &e); err != nil {
+		return err
+	}
 
 	if g.stages[e.Player] == initialMeldStage {
-		return fmt.Errorf("wrong stage action for player: %v", e.Player)
+		return NewError(
+			ErrorCodeWrongStage, "wrong stage action for player: %v", e.Player,
+		)
 	}
 
 	if len(e.UsedCombinations) != 1 {
-		return fmt.Errorf("exactly one combination can be splitted per action")
+		return NewError(
+			ErrorCodeOutOfRange,
+			"exactly one combination can be splitted per action",
+		)
 	}
 
 	stepNumber := e.UsedCombinations[0]
 
 	combination := g.combinationByStepNumber(stepNumber)
 	if combination == nil {
-		return fmt.Errorf("there is no combination with index %v", stepNumber)
+		return NewError(
+			ErrorCodeUnknownCombination,
+			"there is no combination with index %v", stepNumber,
+		)
 	}
 
-	if e.SplitBeforeIndex >= len(combination.Pieces) {
-		return fmt.Errorf(
+	if e.SplitBeforeIndex <= 0 || e.SplitBeforeIndex >= len(combination.Pieces) {
+		return NewError(
+			ErrorCodeOutOfRange,
 			"index %v out of range in combination %v",
 			e.SplitBeforeIndex, stepNumber,
 		)
 	}
 
-	pieces1 := combination.Pieces[:e.SplitBeforeIndex]
-	pieces2 := combination.Pieces[e.SplitBeforeIndex:]
+	pieces1 := append(pack{}, combination.Pieces[:e.SplitBeforeIndex]...)
+	pieces2 := append(pack{}, combination.Pieces[e.SplitBeforeIndex:]...)
 
-	newCombination1 := validCombination(pieces1)
-	newCombination2 := validCombination(pieces2)
+	g.deleteCombinationByStepNumber(stepNumber)
+	declared := combination.declarations()
 
-	if newCombination1 == nil || newCombination2 == nil {
-		return fmt.Errorf(
-			"can't create two valid combinations from splitting combination %v on index %v",
-			stepNumber, e.SplitBeforeIndex,
-		)
+	g.placeCombination(e.Player, draftCombination(pieces1, declared))
+	g.placeCombination(e.Player, draftCombination(pieces2, declared))
+
+	return nil
+}
+
+// Legal interpretations query handler
+//
+// Returns the interpretations of the combination made of the pieces,
+// the first one is used for the jokers which are not declared
+func (g *Game) interpretationsHandle(player_ player, data []byte) *Event {
+	var e EventInterpretations
+	if err := decodeEventData(data, &e); err != nil {
+		return ErrorEvent(err)
 	}
 
-	g.deleteCombinationByStepNumber(stepNumber)
-	g.placeCombination(e.Player, newCombination1)
-	g.placeCombination(e.Player, newCombination2)
+	pieces := []*Piece{}
+	used := map[string]bool{}
+
+	for _, id := range e.Pieces {
+		if used[id] {
+			return ErrorEvent(NewError(
+				ErrorCodeDuplicatePiece, "piece %v is used more than once", id,
+			))
+		}
+		used[id] = true
+
+		p := g.visiblePieceByID(player_, id)
+		if p == nil {
+			return ErrorEvent(NewError(
+				ErrorCodeUnknownPiece,
+				"piece %v is neither in the hand nor on the field", id,
+			))
+		}
+
+		pieces = append(pieces, p)
+	}
+
+	return NewEvent(EventTypeSuccess, g.rules.interpretations(pieces))
+}
+
+// Check that the declared jokers are among the pieces
+// and represent existing pieces
+func validDeclarations(pieces []*Piece, declared map[string]JokerAssignment) error {
+	jokers := map[string]bool{}
+	for _, p := range pieces {
+		if p.Joker {
+			jokers[p.ID] = true
+		}
+	}
+
+	for id, j := range declared {
+		if !jokers[id] {
+			return NewError(
+				ErrorCodeUnknownPiece, "there is no joker %v in combination", id,
+			)
+		}
+
+		if j.Number < MinNumber || j.Number > MaxNumber {
+			return NewError(
+				ErrorCodeOutOfRange,
+				"joker %v number must be from %v to %v", id, MinNumber, MaxNumber,
+			)
+		}
+
+		valid := false
+		for _, c := range colors {
+			valid = valid || c == j.Color
+		}
+
+		if !valid {
+			return NewError(
+				ErrorCodeInvalidData, "joker %v has unknown color %v", id, j.Color,
+			)
+		}
+	}
 
 	return nil
 }
 
+// Merge joker declarations, the later ones take precedence
+func mergeDeclarations(
+	declarations ...map[string]JokerAssignment,
+) map[string]JokerAssignment {
+	merged := map[string]JokerAssignment{}
+
+	for _, d := range declarations {
+		for id, j := range d {
+			merged[id] = j
+		}
+	}
+
+	return merged
+}
+
 // Place combination on the field
 func (g *Game) placeCombination(player_ player, comb *Combination) {
 	s := &step{
@@ -653,28 +1212,55 @@ func (g *Game) deleteCombinationByStepNumber(stepNumber int) {
 	}
 }
 
-// Find piece by its index in the player's hand
-func (g *Game) pieceByIndex(player_ player, pieceIndex int) *Piece {
-	if pieceIndex >= len(g.hands[player_]) {
+// Find piece by its id in the player's hand
+func (g *Game) pieceByID(player_ player, id string) *Piece {
+	index := pack(g.hands[player_]).indexByID(id)
+	if index == -1 {
 		return nil
 	}
-	return g.hands[player_][pieceIndex]
+	return g.hands[player_][index]
+}
+
+// Find piece by its id in the player's hand or on the field
+func (g *Game) visiblePieceByID(player_ player, id string) *Piece {
+	if p := g.pieceByID(player_, id); p != nil {
+		return p
+	}
+
+	for _, c := range g.field {
+		if index := c.Pieces.indexByID(id); index != -1 {
+			return c.Pieces[index]
+		}
+	}
+
+	return nil
 }
 
-// Gather pieces together from player's hand by their indeces
-func (g *Game) gatherPieces(player_ player, pieceIndeces []int) ([]*Piece, int) {
+// Gather pieces together from player's hand by their ids
+func (g *Game) gatherPieces(player_ player, ids []string) ([]*Piece, error) {
 	pieces := []*Piece{}
+	used := map[string]bool{}
+
+	for _, id := range ids {
+		if used[id] {
+			return nil, NewError(
+				ErrorCodeDuplicatePiece, "piece %v is used more than once", id,
+			)
+		}
+		used[id] = true
 
-	for _, pieceIndex := range pieceIndeces {
-		p := g.pieceByIndex(player_, pieceIndex)
-		if p != nil {
-			pieces = append(pieces, g.pieceByIndex(player_, pieceIndex))
-		} else {
-			return nil, pieceIndex
+		p := g.pieceByID(player_, id)
+		if p == nil {
+			return nil, NewError(
+				ErrorCodeUnknownPiece,
+				"player %v doesn't own piece %v", player_, id,
+			)
 		}
+
+		pieces = append(pieces, p)
 	}
 
-	return pieces, -1
+	return pieces, nil
 }
 
 // Add piece to the player's hand
@@ -682,20 +1268,22 @@ func (g *Game) addPieceToHand(player_ player, piece *Piece) {
 	g.hands[player_] = append(g.hands[player_], piece)
 }
 
-// Remove pieces from the player's hand by their indeces
-func (g *Game) removePiecesFromHand(player_ player, pieceIndeces []int) {
-	indeces := pieceIndeces[:]
-	sort.Sort(sort.Reverse(sort.IntSlice(indeces)))
-
-	for _, ind := range indeces {
-		g.removePieceFromHand(player_, ind)
+// Remove pieces from the player's hand by their ids
+func (g *Game) removePiecesFromHand(player_ player, ids []string) {
+	for _, id := range ids {
+		g.removePieceFromHand(player_, id)
 	}
 }
 
-// Remove piece by its index from the player's hand
-func (g *Game) removePieceFromHand(player_ player, pieceIndex int) {
+// Remove piece by its id from the player's hand
+func (g *Game) removePieceFromHand(player_ player, id string) {
+	index := pack(g.hands[player_]).indexByID(id)
+	if index == -1 {
+		return
+	}
+
 	g.hands[player_] = append(
-		g.hands[player_][:pieceIndex],
-		g.hands[player_][pieceIndex+1:]...,
+		g.hands[player_][:index],
+		g.hands[player_][index+1:]...,
 	)
 }
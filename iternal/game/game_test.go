@@ -0,0 +1,302 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package game
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+// Create game with the default rules
+func newTestGame(t *testing.T) *Game {
+	g, err := NewGame(DefaultRuleSet(), 1)
+	if err != nil {
+		t.Fatalf("game is not created: %v", err)
+	}
+	return g
+}
+
+// Add the players and start the game
+func startGame(t *testing.T, g *Game, players ...string) {
+	for _, p := range players {
+		if err := g.AddPlayer(p).Err(); err != nil {
+			t.Fatalf("player %v is not added: %v", p, err)
+		}
+	}
+
+	if err := g.Start(); err != nil {
+		t.Fatalf("game is not started: %v", err)
+	}
+}
+
+// Player who waits for the turn
+func otherPlayer(g *Game) string {
+	for _, p := range g.players {
+		if string(p) != g.CurrentPlayer() {
+			return string(p)
+		}
+	}
+	return ""
+}
+
+// Field and hands of the game
+func snapshot(g *Game) string {
+	s := ""
+	for _, c := range g.field.ordered() {
+		s += fmt.Sprintf("%v %v %v %v\n", c.ID, c.Type, PiecesIDs(c.Pieces), c.Jokers)
+	}
+	for _, p := range g.players {
+		s += fmt.Sprintf("%v %v\n", p, PiecesIDs(g.hands[p]))
+	}
+	return s + fmt.Sprintf("bank %v, turn %v\n", len(g.bank), g.turn)
+}
+
+func TestSetEventPlayer(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want map[string]interface{}
+	}{
+		{"no data", ``, map[string]interface{}{"player": "a"}},
+		{"null data", `null`, map[string]interface{}{"player": "a"}},
+		{"no player", `{"x":1}`, map[string]interface{}{"player": "a", "x": 1.0}},
+		{"other player", `{"player":"b","x":1}`, map[string]interface{}{"player": "a", "x": 1.0}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := setEventPlayer([]byte(test.data), "a")
+			if err != nil {
+				t.Fatalf("data is rejected: %v", err)
+			}
+
+			got := map[string]interface{}{}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("invalid data %s: %v", data, err)
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	if _, err := setEventPlayer([]byte(`["b"]`), "a"); ErrorCodeOf(err) != ErrorCodeInvalidData {
+		t.Errorf("got error %v for not an object, want %v", err, ErrorCodeInvalidData)
+	}
+}
+
+// Player given in the event data is replaced by the one who made the event
+func TestHandleEventRewritesPlayer(t *testing.T) {
+	g := newTestGame(t)
+	startGame(t, g, "a", "b")
+
+	current, other := g.CurrentPlayer(), otherPlayer(g)
+	currentHand := len(g.hands[player(current)])
+	otherHand := len(g.hands[player(other)])
+
+	data, _ := json.Marshal(EventDraw{Player: player(other)})
+	response := g.HandleEvent(current, &Event{Type: EventTypeDraw, Data: data})
+	if err := response.Err(); err != nil {
+		t.Fatalf("draw is rejected: %v", err)
+	}
+
+	if n := len(g.hands[player(current)]); n != currentHand+1 {
+		t.Errorf("%v has %v pieces after the draw, want %v", current, n, currentHand+1)
+	}
+	if n := len(g.hands[player(other)]); n != otherHand {
+		t.Errorf("%v has %v pieces after the other's draw, want %v", other, n, otherHand)
+	}
+
+	entry := g.history.log[len(g.history.log)-1]
+	var e EventDraw
+	if err := json.Unmarshal(entry.Data, &e); err != nil {
+		t.Fatalf("invalid log entry %s: %v", entry.Data, err)
+	}
+	if entry.Player != player(current) || e.Player != player(current) {
+		t.Errorf("draw is recorded as made by %v (data %v), want %v", entry.Player, e.Player, current)
+	}
+}
+
+// Actions out of the player's turn change nothing
+func TestHandleEventOutOfTurn(t *testing.T) {
+	g := newTestGame(t)
+	startGame(t, g, "a", "b")
+
+	current, other := g.CurrentPlayer(), otherPlayer(g)
+	g.stages[player(current)] = mainGameStage
+	g.stages[player(other)] = mainGameStage
+
+	// Current player's draft is on the field
+	piece := g.hands[player(current)][0]
+	added, _ := json.Marshal(EventAddCombination{AddedPieces: []string{piece.ID}})
+	if err := g.HandleEvent(current, &Event{Type: EventTypeAddCombination, Data: added}).Err(); err != nil {
+		t.Fatalf("combination is not added: %v", err)
+	}
+
+	otherPiece := g.hands[player(other)][0]
+	events := []*Event{
+		NewEvent(EventTypeDraw, nil),
+		NewEvent(EventTypeDraw, EventDraw{Player: player(current)}),
+		NewEvent(EventTypeAddCombination, EventAddCombination{
+			Player: player(current), AddedPieces: []string{otherPiece.ID},
+		}),
+		NewEvent(EventTypeAddPiece, EventAddPiece{
+			AddedPieces:      []string{otherPiece.ID},
+			UsedCombinations: []int{g.field.ordered()[0].ID},
+		}),
+		NewEvent(EventTypeRevertTurn, nil),
+		NewEvent(EventTypeCommitTurn, EventCommitTurn{Player: player(current)}),
+	}
+
+	before := snapshot(g)
+	entries := len(g.history.log)
+
+	for _, e := range events {
+		t.Run(string(e.Type), func(t *testing.T) {
+			err := g.HandleEvent(other, e).Err()
+			if code := ErrorCodeOf(err); code != ErrorCodeNotYourTurn {
+				t.Errorf("got error %v (%v), want %v", code, err, ErrorCodeNotYourTurn)
+			}
+
+			var te *TurnError
+			if err := g.handleAction(player(other), e); !errors.As(err, &te) {
+				t.Errorf("got error %v, want turn error", err)
+			} else if te.Player != player(other) || te.Current != player(current) {
+				t.Errorf("got turn error of %v (current %v), want %v (current %v)",
+					te.Player, te.Current, other, current)
+			}
+
+			if after := snapshot(g); after != before {
+				t.Errorf("game is changed:\n%vwas:\n%v", after, before)
+			}
+			if len(g.history.log) != entries {
+				t.Errorf("rejected event is recorded")
+			}
+		})
+	}
+}
+
+// Take the piece from the bank or the hands
+func takePiece(t *testing.T, g *Game, id string) *Piece {
+	if i := g.bank.indexByID(id); i != -1 {
+		p := g.bank[i]
+		g.bank = append(g.bank[:i:i], g.bank[i+1:]...)
+		return p
+	}
+
+	for player_, h := range g.hands {
+		if i := pack(h).indexByID(id); i != -1 {
+			p := h[i]
+			g.hands[player_] = append(h[:i:i], h[i+1:]...)
+			return p
+		}
+	}
+
+	t.Fatalf("there is no piece %v", id)
+	return nil
+}
+
+func TestJokerRetrieval(t *testing.T) {
+	joker := pieceID(1, JokerColor, 1)
+	red8 := pieceID(1, red, 8)
+	blue9 := pieceID(1, blue, 9)
+	black9 := pieceID(1, black, 9)
+
+	tests := []struct {
+		name   string
+		strict bool
+		event  *Event
+
+		// Retrieval is rejected, otherwise the joker
+		// is played and the turn is committed
+		rejected bool
+	}{
+		{
+			name:   "strict replace by represented piece",
+			strict: true,
+			event: NewEvent(EventTypeReplacePiece, EventReplacePiece{
+				AddedPieces: []string{red8}, RemovedPiece: joker, UsedCombinations: []int{1},
+			}),
+		},
+		{
+			name:   "strict replace by other piece",
+			strict: true,
+			event: NewEvent(EventTypeReplacePiece, EventReplacePiece{
+				AddedPieces: []string{blue9}, RemovedPiece: joker, UsedCombinations: []int{1},
+			}),
+			rejected: true,
+		},
+		{
+			name:   "strict remove",
+			strict: true,
+			event: NewEvent(EventTypeRemovePiece, EventRemovePiece{
+				RemovedPiece: joker, UsedCombinations: []int{1},
+			}),
+			rejected: true,
+		},
+		{
+			name:   "remove",
+			strict: false,
+			event: NewEvent(EventTypeRemovePiece, EventRemovePiece{
+				RemovedPiece: joker, UsedCombinations: []int{1},
+			}),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules := DefaultRuleSet()
+			rules.StrictJokerRetrieval = test.strict
+
+			g, err := NewGame(rules, 1)
+			if err != nil {
+				t.Fatalf("game is not created: %v", err)
+			}
+			startGame(t, g, "a", "b")
+
+			current, other := player(g.CurrentPlayer()), player(otherPlayer(g))
+			g.stages[current] = mainGameStage
+
+			// Red 5-6-7 and the joker representing red 8 are on the field
+			run := &Combination{Type: run, Jokers: map[string]JokerAssignment{joker: {8, red}}}
+			for _, id := range []string{pieceID(1, red, 5), pieceID(1, red, 6), pieceID(1, red, 7), joker} {
+				run.Pieces = append(run.Pieces, takePiece(t, g, id))
+			}
+			g.placeCombination(other, run)
+
+			for _, id := range []string{red8, blue9, black9} {
+				g.hands[current] = append(g.hands[current], takePiece(t, g, id))
+			}
+			g.beginTurn()
+
+			err = g.HandleEvent(string(current), test.event).Err()
+			if test.rejected {
+				if code := ErrorCodeOf(err); code != ErrorCodeJokerRetrieval {
+					t.Fatalf("got error %v (%v), want %v", code, err, ErrorCodeJokerRetrieval)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("joker is not retrieved: %v", err)
+			}
+
+			// The retrieved joker is played with the nines
+			played := NewEvent(EventTypeAddCombination, EventAddCombination{
+				AddedPieces: []string{joker, blue9, black9},
+			})
+			if err := g.HandleEvent(string(current), played).Err(); err != nil {
+				t.Fatalf("joker is not played: %v", err)
+			}
+
+			if err := g.HandleEvent(string(current), NewEvent(EventTypeCommitTurn, nil)).Err(); err != nil {
+				t.Errorf("turn is not committed: %v", err)
+			}
+		})
+	}
+}
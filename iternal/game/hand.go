@@ -22,3 +22,18 @@ func (h hand) largestPieceNumber() int {
 
 	return largest
 }
+
+// Sum of the pieces' values in the hand
+func (h hand) value() int {
+	sum := 0
+
+	for _, p := range h {
+		if p.Joker {
+			sum += JokerValue
+		} else {
+			sum += p.Number
+		}
+	}
+
+	return sum
+}
@@ -0,0 +1,117 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package game
+
+// Hint
+//
+// Suggested play: the field combinations to be rearranged and the
+// new combinations made of their pieces and the played pieces from
+// the hand. There is nothing to play if no pieces are played
+type Hint struct {
+	Replaced     []int      `json:"replaced"`
+	Combinations [][]string `json:"combinations"`
+	Played       []string   `json:"played"`
+	HintsLeft    int        `json:"hintsLeft"`
+}
+
+// Hinter
+//
+// Suggests a play for the hand and the field. Hints are computed
+// outside of the game, so the room isn't blocked by the search. The
+// pieces and the combinations must not be changed. Only the hand can
+// be played if meld is true
+type Hinter interface {
+	Hint(rules RuleSet, hand []*Piece, field []FieldCombination, meld bool) *Hint
+}
+
+// Hint query
+//
+// Made on the player's turn, contains copies of the hand and the
+// field, so the hint can be computed outside of the game
+type HintQuery struct {
+	Player  string
+	Rules   RuleSet
+	Hand    []*Piece
+	Field   []FieldCombination
+	Meld    bool
+	version int
+}
+
+// Compute the hint for the query
+func (q *HintQuery) Hint(h Hinter) *Hint {
+	return h.Hint(q.Rules, q.Hand, q.Field, q.Meld)
+}
+
+// Hints left for the player
+func (g *Game) hintsLeft(player_ player) int {
+	return g.rules.HintsPerGame - g.hints[player_]
+}
+
+// Check the player's hint event and make the query
+func (g *Game) QueryHint(p string, e *Event) (*HintQuery, error) {
+	player_ := player(p)
+
+	var data EventHint
+	if err := decodeEventData(e.Data, &data); err != nil {
+		return nil, err
+	}
+
+	if !g.IsStarted() {
+		return nil, NewError(ErrorCodeNotStarted, "game is not started yet")
+	}
+
+	if g.phase != PhaseStarted {
+		return nil, NewError(ErrorCodeFinished, "game is already %v", g.phase)
+	}
+
+	if current := player(g.CurrentPlayer()); current != player_ {
+		return nil, &TurnError{Player: player_, Current: current}
+	}
+
+	if g.hintsLeft(player_) <= 0 {
+		return nil, NewError(
+			ErrorCodeHintLimit, "player %v has no hints left", player_,
+		)
+	}
+
+	return &HintQuery{
+		Player:  p,
+		Rules:   g.rules,
+		Hand:    append([]*Piece{}, g.hands[player_]...),
+		Field:   g.field.ordered(),
+		Meld:    g.stages[player_] == initialMeldStage,
+		version: g.version,
+	}, nil
+}
+
+// Answer the hint query
+//
+// Hints suggesting a play are counted against the rule set limit
+// and recorded in the game log, empty ones are free. The hint is
+// rejected if the game changed after the query was made
+func (g *Game) AnswerHint(q *HintQuery, hint *Hint) *Event {
+	player_ := player(q.Player)
+
+	if g.version != q.version {
+		return ErrorEvent(NewError(
+			ErrorCodeStaleHint, "game changed while the hint was computed",
+		))
+	}
+
+	if len(hint.Played) > 0 {
+		if g.hintsLeft(player_) <= 0 {
+			return ErrorEvent(NewError(
+				ErrorCodeHintLimit, "player %v has no hints left", player_,
+			))
+		}
+
+		g.hints[player_] += 1
+		g.record(player_, EventTypeHint, EventHint{Player: player_})
+	}
+
+	hint.HintsLeft = g.hintsLeft(player_)
+
+	return NewEvent(EventTypeSuccess, hint)
+}
@@ -0,0 +1,147 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package game
+
+import "testing"
+
+// Hinter suggesting the first piece of the hand, or nothing
+type testHinter struct {
+	empty bool
+}
+
+func (h testHinter) Hint(rules RuleSet, hand []*Piece, field []FieldCombination, meld bool) *Hint {
+	hint := &Hint{Replaced: []int{}, Combinations: [][]string{}, Played: []string{}}
+	if !h.empty {
+		hint.Played = append(hint.Played, hand[0].ID)
+	}
+	return hint
+}
+
+// Create started game where every player has the given number of hints
+func newHintGame(t *testing.T, hints int) *Game {
+	rules := DefaultRuleSet()
+	rules.HintsPerGame = hints
+
+	g, err := NewGame(rules, 1)
+	if err != nil {
+		t.Fatalf("game is not created: %v", err)
+	}
+	startGame(t, g, "a", "b")
+
+	return g
+}
+
+// Query and answer the current player's hint
+func askHint(t *testing.T, g *Game, h Hinter) *Event {
+	q, err := g.QueryHint(g.CurrentPlayer(), &Event{Type: EventTypeHint})
+	if err != nil {
+		t.Fatalf("hint is not queried: %v", err)
+	}
+	return g.AnswerHint(q, q.Hint(h))
+}
+
+func TestHintLimit(t *testing.T) {
+	g := newHintGame(t, 2)
+	p := g.CurrentPlayer()
+
+	for left := 1; left >= 0; left-- {
+		e := askHint(t, g, testHinter{})
+		if err := e.Err(); err != nil {
+			t.Fatalf("hint is rejected: %v", err)
+		}
+
+		var hint Hint
+		if err := e.DecodeData(&hint); err != nil {
+			t.Fatalf("invalid hint: %v", err)
+		}
+		if hint.HintsLeft != left {
+			t.Errorf("%v hints left, want %v", hint.HintsLeft, left)
+		}
+	}
+
+	_, err := g.QueryHint(p, &Event{Type: EventTypeHint})
+	if ErrorCodeOf(err) != ErrorCodeHintLimit {
+		t.Errorf("query over the limit got %v, want %v", err, ErrorCodeHintLimit)
+	}
+
+	entries := 0
+	for _, entry := range g.Log().Entries {
+		if entry.Type == EventTypeHint {
+			entries += 1
+		}
+	}
+	if entries != 2 {
+		t.Errorf("%v hints are logged, want 2", entries)
+	}
+}
+
+func TestHintLimitOfConcurrentQueries(t *testing.T) {
+	g := newHintGame(t, 1)
+
+	first, err := g.QueryHint(g.CurrentPlayer(), &Event{Type: EventTypeHint})
+	if err != nil {
+		t.Fatalf("hint is not queried: %v", err)
+	}
+	second, err := g.QueryHint(g.CurrentPlayer(), &Event{Type: EventTypeHint})
+	if err != nil {
+		t.Fatalf("hint is not queried: %v", err)
+	}
+
+	if err := g.AnswerHint(first, first.Hint(testHinter{})).Err(); err != nil {
+		t.Fatalf("hint is rejected: %v", err)
+	}
+
+	// Charged hint doesn't make the second query stale, the limit stops it
+	err = g.AnswerHint(second, second.Hint(testHinter{})).Err()
+	if ErrorCodeOf(err) != ErrorCodeHintLimit {
+		t.Errorf("second hint got %v, want %v", err, ErrorCodeHintLimit)
+	}
+}
+
+func TestStaleHint(t *testing.T) {
+	g := newHintGame(t, 3)
+	p := g.CurrentPlayer()
+
+	q, err := g.QueryHint(p, &Event{Type: EventTypeHint})
+	if err != nil {
+		t.Fatalf("hint is not queried: %v", err)
+	}
+
+	if err := g.HandleEvent(p, &Event{Type: EventTypeDraw}).Err(); err != nil {
+		t.Fatalf("piece is not drawn: %v", err)
+	}
+
+	err = g.AnswerHint(q, q.Hint(testHinter{})).Err()
+	if ErrorCodeOf(err) != ErrorCodeStaleHint {
+		t.Errorf("hint got %v, want %v", err, ErrorCodeStaleHint)
+	}
+	if left := g.hintsLeft(player(p)); left != 3 {
+		t.Errorf("%v hints left, want 3", left)
+	}
+}
+
+func TestEmptyHintIsFree(t *testing.T) {
+	g := newHintGame(t, 1)
+	entries := len(g.Log().Entries)
+
+	for i := 0; i < 3; i++ {
+		e := askHint(t, g, testHinter{empty: true})
+		if err := e.Err(); err != nil {
+			t.Fatalf("hint is rejected: %v", err)
+		}
+
+		var hint Hint
+		if err := e.DecodeData(&hint); err != nil {
+			t.Fatalf("invalid hint: %v", err)
+		}
+		if hint.HintsLeft != 1 {
+			t.Errorf("%v hints left, want 1", hint.HintsLeft)
+		}
+	}
+
+	if len(g.Log().Entries) != entries {
+		t.Errorf("empty hints are logged")
+	}
+}
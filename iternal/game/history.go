@@ -8,16 +8,20 @@ package game
 //
 // Store information about game progress
 type history struct {
+	seed         int64
+	log          []LogEntry
 	combinations field
 	firstStep    *step
 	lastStep     *step
 }
 
 // Create history
-func createHistory() *history {
+func createHistory(seed int64) *history {
 	s := &step{0, "", nil, nil}
 
 	return &history{
+		seed:         seed,
+		log:          []LogEntry{},
 		combinations: field{},
 		firstStep:    s,
 		lastStep:     s,
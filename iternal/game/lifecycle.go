@@ -0,0 +1,136 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package game
+
+import "time"
+
+// Game phase
+type Phase string
+
+const (
+	// Players join, there are not enough of them to start
+	PhaseOpen Phase = "open"
+	// Enough players joined, waiting for all of them to be ready
+	PhaseReadyCheck Phase = "readyCheck"
+	// All players are ready, the game starts after the countdown
+	PhaseCountdown Phase = "countdown"
+	// Game is in progress
+	PhaseStarted Phase = "started"
+	// Game is finished and the scores are counted
+	PhaseFinished Phase = "finished"
+	// Game is stopped because there are not enough players left
+	PhaseAbandoned Phase = "abandoned"
+)
+
+// Allowed transitions between the phases
+var phaseTransitions map[Phase]map[Phase]bool = map[Phase]map[Phase]bool{
+	PhaseOpen: {
+		PhaseReadyCheck: true,
+	},
+	PhaseReadyCheck: {
+		PhaseOpen:      true,
+		PhaseCountdown: true,
+		PhaseStarted:   true,
+	},
+	PhaseCountdown: {
+		PhaseOpen:       true,
+		PhaseReadyCheck: true,
+		PhaseStarted:    true,
+	},
+	PhaseStarted: {
+		PhaseFinished:  true,
+		PhaseAbandoned: true,
+	},
+	PhaseFinished:  {},
+	PhaseAbandoned: {},
+}
+
+// Check if players can join the game in the phase
+func (p Phase) seating() bool {
+	return p == PhaseOpen || p == PhaseReadyCheck || p == PhaseCountdown
+}
+
+// Move the game to the phase
+func (g *Game) setPhase(p Phase) error {
+	if p == g.phase {
+		return nil
+	}
+
+	if !phaseTransitions[g.phase][p] {
+		return NewError(
+			ErrorCodeWrongPhase, "game can't go from %v to %v", g.phase, p,
+		)
+	}
+
+	g.phase = p
+
+	if p == PhaseCountdown {
+		g.countdown = g.clock.Now().Add(
+			time.Duration(g.rules.CountdownSeconds) * time.Second,
+		)
+	}
+
+	return nil
+}
+
+// Current phase of the game
+func (g *Game) Phase() Phase {
+	return g.phase
+}
+
+// Seconds left before the game starts, zero if there is no countdown
+func (g *Game) CountdownLeft() int {
+	if g.phase != PhaseCountdown {
+		return 0
+	}
+
+	left := g.countdown.Sub(g.clock.Now())
+	if left <= 0 {
+		return 0
+	}
+
+	return int((left + time.Second - 1) / time.Second)
+}
+
+// Start the game if its countdown is over
+//
+// Returns true if the game was started
+func (g *Game) CheckCountdown() bool {
+	if g.phase != PhaseCountdown || g.clock.Now().Before(g.countdown) {
+		return false
+	}
+
+	return g.Start() == nil
+}
+
+// Update the phase after players joined, left or got ready
+//
+// Before the start the phase depends on the number of players
+// and their readiness, after it the game is abandoned when there
+// are not enough players left
+func (g *Game) updateSeating() {
+	if !g.phase.seating() {
+		if g.phase == PhaseStarted && len(g.players) < g.rules.MinPlayersNumber {
+			g.setPhase(PhaseAbandoned)
+		}
+		return
+	}
+
+	if len(g.players) < g.rules.MinPlayersNumber {
+		g.setPhase(PhaseOpen)
+		return
+	}
+
+	for _, p := range g.players {
+		if !g.readyPlayers[p] {
+			g.setPhase(PhaseReadyCheck)
+			return
+		}
+	}
+
+	if g.phase != PhaseCountdown {
+		g.setPhase(PhaseCountdown)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package game
+
+import (
+	"fmt"
+
+	"github.com/goccy/go-json"
+)
+
+// Log entry
+//
+// Contains accepted event and its index in the game log
+type LogEntry struct {
+	Index  int             `json:"index"`
+	Player player          `json:"player"`
+	Type   EventType       `json:"type"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+// Game log
+//
+// Contains the seed, the rule set and every accepted event
+// in order, which is enough to rebuild the game
+type Log struct {
+	Seed    int64      `json:"seed"`
+	Rules   RuleSet    `json:"rules"`
+	Entries []LogEntry `json:"entries"`
+}
+
+// Append accepted event to the game log
+func (g *Game) record(player_ player, t EventType, data interface{}) {
+	raw, _ := json.Marshal(data)
+
+	g.history.log = append(g.history.log, LogEntry{
+		Index:  len(g.history.log),
+		Player: player_,
+		Type:   t,
+		Data:   raw,
+	})
+
+	if t != EventTypeChat && t != EventTypeHint {
+		g.version += 1
+	}
+}
+
+// Add chat message to the game log
+//
+// Returns the chat event to be sent to the clients
+func (g *Game) RecordChat(p string, channel string, text string, emote string) *Event {
+	e := EventChat{
+		Player:  player(p),
+		Channel: channel,
+		Text:    text,
+		Emote:   emote,
+	}
+
+	g.record(e.Player, EventTypeChat, e)
+
+	return NewEvent(EventTypeChat, e)
+}
+
+// Game log
+func (g *Game) Log() *Log {
+	return &Log{
+		Seed:    g.history.seed,
+		Rules:   g.rules,
+		Entries: append([]LogEntry{}, g.history.log...),
+	}
+}
+
+// Rebuild the game from the log
+//
+// Only the first steps entries of the log are applied
+func Replay(l *Log, steps int) (*Game, error) {
+	if steps < 0 || steps > len(l.Entries) {
+		return nil, fmt.Errorf(
+			"steps number must be from 0 to %v", len(l.Entries),
+		)
+	}
+
+	g, err := NewGame(l.Rules, l.Seed)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range l.Entries[:steps] {
+		if err := g.applyLogEntry(entry); err != nil {
+			return nil, fmt.Errorf("log entry %v: %v", entry.Index, err)
+		}
+	}
+
+	return g, nil
+}
+
+// Apply log entry to the game
+func (g *Game) applyLogEntry(entry LogEntry) error {
+	switch entry.Type {
+	case EventTypeJoin:
+		var e EventJoin
+		if err := decodeEventData(entry.Data, &e); err != nil {
+			return err
+		}
+
+		return g.AddPlayer(e.Player).Err()
+	case EventTypeLeave:
+		var e EventLeave
+		if err := decodeEventData(entry.Data, &e); err != nil {
+			return err
+		}
+
+		return g.RemovePlayer(e.Player)
+	case EventTypeTimeout:
+		if g.CurrentPlayer() == "" {
+			return NewError(ErrorCodeNotStarted, "there is no turn to time out")
+		}
+
+		g.timeout()
+	case EventTypeSkip:
+		return g.SkipTurn()
+	case EventTypeStart:
+		return g.Start()
+	case EventTypeChat:
+		g.record(entry.Player, entry.Type, entry.Data)
+	case EventTypeHint:
+		g.hints[entry.Player] += 1
+		g.record(entry.Player, entry.Type, entry.Data)
+	default:
+		return g.handleAction(
+			entry.Player, &Event{Type: entry.Type, Data: entry.Data},
+		)
+	}
+
+	return nil
+}
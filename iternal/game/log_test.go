@@ -0,0 +1,166 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package game
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// Field, hands and the bank of the game
+func fullSnapshot(g *Game) string {
+	return snapshot(g) + fmt.Sprint(PiecesIDs(g.bank))
+}
+
+// Valid combination of three pieces from the hand
+func findCombination(g *Game, hand []*Piece) []string {
+	for i := range hand {
+		for j := i + 1; j < len(hand); j++ {
+			for k := j + 1; k < len(hand); k++ {
+				pieces := pack{hand[i], hand[j], hand[k]}
+				if g.rules.validCombination(pieces, nil) != nil {
+					return PiecesIDs(pieces)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func TestReplay(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	rules := DefaultRuleSet()
+	rules.InitialMeldSum = 0
+
+	g, err := NewGame(rules, 7)
+	if err != nil {
+		t.Fatalf("game is not created: %v", err)
+	}
+	g.SetClock(clock)
+
+	// Snapshots of the game by the number of the log entries
+	snapshots := map[int]string{0: fullSnapshot(g)}
+	capture := func() {
+		snapshots[len(g.history.log)] = fullSnapshot(g)
+	}
+
+	for _, p := range []string{"a", "b", "c"} {
+		if err := g.AddPlayer(p).Err(); err != nil {
+			t.Fatalf("player %v is not added: %v", p, err)
+		}
+		capture()
+	}
+	if err := g.Start(); err != nil {
+		t.Fatalf("game is not started: %v", err)
+	}
+	capture()
+
+	handle := func(t_ EventType, data interface{}) {
+		p := g.CurrentPlayer()
+		raw, _ := json.Marshal(data)
+		if err := g.HandleEvent(p, &Event{Type: t_, Data: raw}).Err(); err != nil {
+			t.Fatalf("%v of %v is rejected: %v", t_, p, err)
+		}
+		capture()
+	}
+
+	commits := 0
+	for turn := 0; turn < 100 && commits < 2; turn++ {
+		p := player(g.CurrentPlayer())
+		ids := findCombination(g, g.hands[p])
+		if ids == nil {
+			handle(EventTypeDraw, nil)
+			continue
+		}
+
+		if g.stages[p] == initialMeldStage {
+			handle(EventTypeInitialMeld, EventInitialMeld{Combinations: [][]string{ids}})
+		} else {
+			handle(EventTypeAddCombination, EventAddCombination{AddedPieces: ids})
+			commits += 1
+		}
+
+		if player(g.CurrentPlayer()) == p {
+			handle(EventTypeCommitTurn, nil)
+		}
+	}
+	if commits < 2 {
+		t.Fatalf("only %v combinations are added", commits)
+	}
+
+	clock.advance(time.Duration(rules.TimeLimitSeconds+1) * time.Second)
+	if !g.CheckTimeout() {
+		t.Fatalf("turn is not timed out")
+	}
+	capture()
+
+	if err := g.RemovePlayer(otherPlayer(g)); err != nil {
+		t.Fatalf("player is not removed: %v", err)
+	}
+	capture()
+
+	handle(EventTypeDraw, nil)
+
+	// The log is replayed after the round trip through JSON
+	data, err := json.Marshal(g.Log())
+	if err != nil {
+		t.Fatalf("log is not encoded: %v", err)
+	}
+	var l Log
+	if err := json.Unmarshal(data, &l); err != nil {
+		t.Fatalf("log is not decoded: %v", err)
+	}
+
+	types := map[EventType]bool{}
+	for _, entry := range l.Entries {
+		types[entry.Type] = true
+	}
+	for _, t_ := range []EventType{EventTypeDraw, EventTypeCommitTurn, EventTypeTimeout, EventTypeLeave} {
+		if !types[t_] {
+			t.Errorf("log has no %v entries", t_)
+		}
+	}
+
+	for steps := 0; steps <= len(l.Entries); steps++ {
+		replayed, err := Replay(&l, steps)
+		if err != nil {
+			t.Fatalf("replay of %v steps failed: %v", steps, err)
+		}
+
+		want, ok := snapshots[steps]
+		if !ok {
+			continue
+		}
+		if got := fullSnapshot(replayed); got != want {
+			t.Errorf("replay of %v steps:\n%v\nwant:\n%v", steps, got, want)
+		}
+	}
+
+	if _, err := Replay(&l, len(l.Entries)+1); err == nil {
+		t.Errorf("replay beyond the log is accepted")
+	}
+}
+
+func TestSeededDeal(t *testing.T) {
+	deal := func(seed int64) string {
+		g, err := NewGame(DefaultRuleSet(), seed)
+		if err != nil {
+			t.Fatalf("game is not created: %v", err)
+		}
+		startGame(t, g, "a", "b", "c")
+		return fullSnapshot(g)
+	}
+
+	if deal(42) != deal(42) {
+		t.Errorf("games with the same seed deal different hands")
+	}
+	if deal(42) == deal(43) {
+		t.Errorf("games with different seeds deal the same hands")
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package game
+
+import "fmt"
+
+// Match
+//
+// Series of games (rounds) played until one of the players
+// reaches the target score. Accumulates players' scores
+// across the rounds
+type Match struct {
+	targetScore int
+	rules       RuleSet
+	rounds      []*Game
+	recorded    int
+	totals      map[player]int
+	finished    bool
+	winner      player
+}
+
+// Create new match
+func NewMatch(targetScore int, rules RuleSet) (*Match, error) {
+	if targetScore <= 0 {
+		return nil, fmt.Errorf("target score must be positive")
+	}
+
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Match{
+		targetScore: targetScore,
+		rules:       rules,
+		rounds:      []*Game{},
+		totals:      map[player]int{},
+	}, nil
+}
+
+// Start new round with the given seed
+func (m *Match) NewRound(seed int64) (*Game, error) {
+	if m.finished {
+		return nil, fmt.Errorf("match is already finished")
+	}
+
+	if m.recorded != len(m.rounds) {
+		return nil, fmt.Errorf("previous round is not recorded yet")
+	}
+
+	g, err := NewGame(m.rules, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	m.rounds = append(m.rounds, g)
+
+	return g, nil
+}
+
+// Add scores of the finished current round to the match totals
+//
+// Abandoned rounds are recorded with no scores
+func (m *Match) RecordRound() error {
+	if m.recorded == len(m.rounds) {
+		return fmt.Errorf("there is no round to record")
+	}
+
+	g := m.rounds[len(m.rounds)-1]
+	if !g.IsFinished() && g.Phase() != PhaseAbandoned {
+		return fmt.Errorf("round is not finished yet")
+	}
+
+	for p, s := range g.scores {
+		m.totals[p] += s
+	}
+	m.recorded += 1
+
+	for _, p := range g.players {
+		s := m.totals[p]
+		if s < m.targetScore {
+			continue
+		}
+
+		if !m.finished || s > m.totals[m.winner] {
+			m.finished = true
+			m.winner = p
+		}
+	}
+
+	return nil
+}
+
+// Accumulated scores of the players
+func (m *Match) Totals() map[string]int {
+	totals := map[string]int{}
+
+	for p, s := range m.totals {
+		totals[string(p)] = s
+	}
+
+	return totals
+}
+
+// Check if match is finished
+func (m *Match) IsFinished() bool {
+	return m.finished
+}
+
+// Player who reached the target score with the highest total
+func (m *Match) Winner() string {
+	return string(m.winner)
+}
@@ -8,15 +8,18 @@ package game
 type pack []*Piece
 
 // Create initial pack (bank)
-func createInitialPack() pack {
+func createInitialPack(rules *RuleSet) pack {
 	b := pack{}
 
-	for d := 0; d < DecksNumber; d++ {
-		b = append(b, createPiece(JokerNumber, JokerColor, true))
+	for d := 0; d < rules.DecksNumber; d++ {
+		for j := 1; j <= rules.JokersPerDeck; j++ {
+			id := pieceID(d+1, JokerColor, j)
+			b = append(b, createPiece(id, JokerNumber, JokerColor, true))
+		}
 
 		for _, c := range colors {
 			for i := MinNumber; i <= MaxNumber; i++ {
-				p := createPiece(i, c, false)
+				p := createPiece(pieceID(d+1, c, i), i, c, false)
 				b = append(b, p)
 			}
 		}
@@ -24,3 +27,13 @@ func createInitialPack() pack {
 
 	return b
 }
+
+// Find index of the piece with the given id, -1 if there is no such piece
+func (p pack) indexByID(id string) int {
+	for i, piece := range p {
+		if piece.ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -5,39 +5,48 @@
 package game
 
 import (
+	"fmt"
 	"sort"
+	"strings"
 )
 
 // Piece
 //
-// Contains information about piece's nubmer,
-// color and flag is it joker or not
+// Contains information about piece's id, nubmer,
+// color and flag is it joker or not. Pieces are never
+// changed after the pack is created
 type Piece struct {
-	Number int   `json:"number"`
-	Color  color `json:"color"`
-	Joker  bool  `json:"joker"`
+	ID     string `json:"id"`
+	Number int    `json:"number"`
+	Color  color  `json:"color"`
+	Joker  bool   `json:"joker"`
 }
 
 // Create new piece
-func createPiece(number int, color_ color, joker bool) *Piece {
-	return &Piece{number, color_, joker}
+func createPiece(id string, number int, color_ color, joker bool) *Piece {
+	return &Piece{id, number, color_, joker}
 }
 
-// Sort the given pieces
-func sortPieces(pieces_ []*Piece) []*Piece {
-	pieces := pieces_[:]
-
-	sort.Slice(pieces, func(i, j int) bool {
-		return pieces[i].Number < pieces[j].Number
-	})
-
-	return pieces
+// Create piece id
+//
+// Unique for every piece in the bank: made of the deck number,
+// the color and the number (jokers are numbered inside the deck)
+func pieceID(deck int, color_ color, number int) string {
+	return fmt.Sprintf("%v-%v-%v", deck, color_, number)
 }
 
-// Set default joker parameters to the joker piece
-func (p *Piece) clearIfJoker() {
-	if p.Joker {
-		p.Number = JokerNumber
-		p.Color = JokerColor
+// Ids of the pieces
+func PiecesIDs(pieces []*Piece) []string {
+	ids := []string{}
+	for _, p := range pieces {
+		ids = append(ids, p.ID)
 	}
+	return ids
+}
+
+// Key of the set of pieces, the same for any order of them
+func PiecesKey(pieces []*Piece) string {
+	ids := PiecesIDs(pieces)
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
 }
@@ -0,0 +1,151 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package game
+
+import "fmt"
+
+// Rule set
+//
+// Contains game rules, so every game can be played
+// with its own house rules
+type RuleSet struct {
+	// Number of pieces a player has at the beginning
+	HandSize int `json:"handSize"`
+
+	// Number of decks in the bank at the beginning
+	DecksNumber int `json:"decksNumber"`
+	// Number of jokers in every deck
+	JokersPerDeck int `json:"jokersPerDeck"`
+
+	// Time limit for a move
+	TimeLimitSeconds int `json:"timeLimitSeconds"`
+	// Countdown before the start when all the players are ready
+	CountdownSeconds int `json:"countdownSeconds"`
+	// Penalty size (in pieces) for exceeding time limit for a move
+	// or leaving the field invalid at the end of it
+	PenaltySize int `json:"penaltySize"`
+
+	// Initial meld sum minimal value
+	InitialMeldSum int `json:"initialMeldSum"`
+
+	// Minimal size of the group combination type
+	MinGroupSize int `json:"minGroupSize"`
+	// Maximal size of the group combination type
+	MaxGroupSize int `json:"maxGroupSize"`
+	// Minimal size of the run combination type
+	MinRunSize int `json:"minRunSize"`
+
+	// Joker retrieved from the field must be replaced by the piece
+	// it represents (it can't be just taken to the hand) and played
+	// again in the same turn
+	StrictJokerRetrieval bool `json:"strictJokerRetrieval"`
+
+	// Number of hints every player can get during the game
+	HintsPerGame int `json:"hintsPerGame"`
+
+	// Minimal number of players in the game
+	MinPlayersNumber int `json:"minPlayersNumber"`
+	// Maximal number of players in the game
+	MaxPlayersNumber int `json:"maxPlayersNumber"`
+}
+
+// Default rule set
+func DefaultRuleSet() RuleSet {
+	return RuleSet{
+		HandSize:         14,
+		DecksNumber:      2,
+		JokersPerDeck:    1,
+		TimeLimitSeconds: 60,
+		CountdownSeconds: 5,
+		PenaltySize:      3,
+		InitialMeldSum:   30,
+		MinGroupSize:     3,
+		MaxGroupSize:     4,
+		MinRunSize:       3,
+
+		StrictJokerRetrieval: true,
+
+		HintsPerGame: 3,
+
+		MinPlayersNumber: 2,
+		MaxPlayersNumber: 4,
+	}
+}
+
+// Check if the rule set is consistent
+func (r RuleSet) Validate() error {
+	if r.HandSize <= 0 {
+		return fmt.Errorf("hand size must be positive")
+	}
+
+	if r.DecksNumber <= 0 {
+		return fmt.Errorf("decks number must be positive")
+	}
+
+	if r.JokersPerDeck < 0 || r.JokersPerDeck > MaxJokersPerDeck {
+		return fmt.Errorf(
+			"jokers per deck must be from 0 to %v", MaxJokersPerDeck,
+		)
+	}
+
+	if r.TimeLimitSeconds <= 0 {
+		return fmt.Errorf("time limit must be positive")
+	}
+
+	if r.CountdownSeconds < 0 {
+		return fmt.Errorf("countdown can't be negative")
+	}
+
+	if r.PenaltySize < 0 {
+		return fmt.Errorf("penalty size can't be negative")
+	}
+
+	if r.InitialMeldSum < 0 {
+		return fmt.Errorf("initial meld sum can't be negative")
+	}
+
+	if r.MinGroupSize < 2 || r.MinGroupSize > r.MaxGroupSize {
+		return fmt.Errorf(
+			"group size must be from 2 to %v, got from %v to %v",
+			len(colors), r.MinGroupSize, r.MaxGroupSize,
+		)
+	}
+
+	if r.MaxGroupSize > len(colors) {
+		return fmt.Errorf(
+			"max group size can't be greater than %v", len(colors),
+		)
+	}
+
+	if r.MinRunSize < 2 || r.MinRunSize > MaxNumber-MinNumber+1 {
+		return fmt.Errorf(
+			"min run size must be from 2 to %v", MaxNumber-MinNumber+1,
+		)
+	}
+
+	if r.HintsPerGame < 0 {
+		return fmt.Errorf("hints per game can't be negative")
+	}
+
+	if r.MinPlayersNumber < 1 || r.MinPlayersNumber > r.MaxPlayersNumber {
+		return fmt.Errorf(
+			"players number must be from 1 to max players number",
+		)
+	}
+
+	if r.MaxPlayersNumber*r.HandSize > r.packSize() {
+		return fmt.Errorf(
+			"%v pieces are not enough to deal %v pieces to %v players",
+			r.packSize(), r.HandSize, r.MaxPlayersNumber,
+		)
+	}
+
+	return nil
+}
+
+// Number of pieces in the initial pack
+func (r RuleSet) packSize() int {
+	return r.DecksNumber * (r.JokersPerDeck + len(colors)*(MaxNumber-MinNumber+1))
+}
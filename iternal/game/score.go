@@ -0,0 +1,52 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package game
+
+// Finish the game and count the scores
+//
+// Every loser subtracts the difference between the value of his hand
+// and the value of the winner's hand, the winner gains the sum of
+// the differences
+func (g *Game) finish(winner player) {
+	g.setPhase(PhaseFinished)
+	g.winner = winner
+
+	winnerValue := g.hands[winner].value()
+	g.scores[winner] = 0
+
+	for _, p := range g.players {
+		if p == winner {
+			continue
+		}
+
+		diff := g.hands[p].value() - winnerValue
+		g.scores[p] = -diff
+		g.scores[winner] += diff
+	}
+}
+
+// Find the player with the lowest value of the hand
+func (g *Game) lowestHandPlayer() player {
+	lowest := g.players[0]
+
+	for _, p := range g.players[1:] {
+		if g.hands[p].value() < g.hands[lowest].value() {
+			lowest = p
+		}
+	}
+
+	return lowest
+}
+
+// Scores of the finished game
+func (g *Game) Scores() map[string]int {
+	scores := map[string]int{}
+
+	for p, s := range g.scores {
+		scores[string(p)] = s
+	}
+
+	return scores
+}
@@ -17,12 +17,34 @@ const (
 )
 
 // Get available event for the stage
-func (s stage) availableEvents() []EventType {
+//
+// Depends on whether it is the player's turn, whether the field
+// was changed during the turn and whether the bank is empty
+func (s stage) availableEvents(turn bool, changed bool, bankEmpty bool) []EventType {
 	if s == systemStage {
 		return initialEvents[:]
 	}
+
+	events := []EventType{}
+	if !turn {
+		return events
+	}
+
 	if s == initialMeldStage {
-		return initialMeldEvents[:]
+		events = append(events, initialMeldEvents[:]...)
+	} else {
+		events = append(events, mainEvents[:]...)
+	}
+
+	if changed {
+		events = append(events, changedFieldEvents[:]...)
+	}
+
+	if bankEmpty {
+		events = append(events, EventTypePass)
+	} else {
+		events = append(events, EventTypeDraw)
 	}
-	return mainEvents[:]
+
+	return events
 }
@@ -7,17 +7,46 @@ package game
 import "encoding/json"
 
 type State struct {
-	Turn bool `jso:"turn"`
-	// Field           field       `json:"field"`
-	Hand            hand        `json:"hand"`
-	AvailableEvents []EventType `json:"availableEvents"`
-	Started         bool        `json:"started"`
-	Finished        bool        `json:"finished"`
-	Winner          player      `json:"winner"`
-	Error           string      `json:"error"`
+	Turn            bool               `json:"turn"`
+	Field           []FieldCombination `json:"field"`
+	BankSize        int                `json:"bankSize"`
+	Opponents       []Opponent         `json:"opponents"`
+	Hand            hand               `json:"hand"`
+	Hands           map[player]hand    `json:"hands,omitempty"`
+	AvailableEvents []EventType        `json:"availableEvents"`
+	TimeLeft        int                `json:"timeLeft"`
+	Phase           Phase              `json:"phase"`
+	Countdown       int                `json:"countdown"`
+	Started         bool               `json:"started"`
+	Finished        bool               `json:"finished"`
+	Winner          player             `json:"winner"`
+	Scores          map[player]int     `json:"scores"`
+	HintsLeft       int                `json:"hintsLeft"`
+	Rules           RuleSet            `json:"rules"`
+	Seed            *int64             `json:"seed,omitempty"`
+	Error           string             `json:"error"`
 }
 
 func (s State) ToJSON() []byte {
 	bytes, _ := json.Marshal(s)
 	return bytes
 }
+
+// Combination on the field
+//
+// Identified by the number of the step it was placed on
+type FieldCombination struct {
+	ID     int                        `json:"id"`
+	Owner  player                     `json:"owner"`
+	Type   combinationType            `json:"type"`
+	Pieces pack                       `json:"pieces"`
+	Jokers map[string]JokerAssignment `json:"jokers,omitempty"`
+}
+
+// Opponent
+//
+// Contains opponent's name and number of pieces in the hand
+type Opponent struct {
+	Player player `json:"player"`
+	Pieces int    `json:"pieces"`
+}
@@ -0,0 +1,158 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package game
+
+import (
+	"time"
+
+	mapset "github.com/deckarep/golang-set/v2"
+)
+
+// Turn workspace
+//
+// Keeps the field and the player's hand as they were at the beginning
+// of the turn, so the player can freely rearrange the table and then
+// commit or revert all the changes at once.
+// Jokers on the field are kept with the values they represent,
+// so the retrieved ones can be checked
+type workspace struct {
+	player     player
+	field      field
+	hand       hand
+	jokers     map[string]JokerAssignment
+	retrieved  map[*Piece]bool
+	lastStep   *step
+	stepNumber int
+	deadline   time.Time
+}
+
+// Create workspace for the current player's turn
+func (g *Game) beginTurn() {
+	player_ := g.players[g.turn]
+
+	ws := &workspace{
+		player:     player_,
+		field:      g.field.copy(),
+		hand:       append(hand{}, g.hands[player_]...),
+		jokers:     map[string]JokerAssignment{},
+		retrieved:  map[*Piece]bool{},
+		lastStep:   g.history.lastStep,
+		stepNumber: g.stepNumber,
+		deadline:   g.clock.Now().Add(time.Duration(g.rules.TimeLimitSeconds) * time.Second),
+	}
+
+	for _, c := range g.field {
+		for id, j := range c.Jokers {
+			ws.jokers[id] = j
+		}
+	}
+
+	g.workspace = ws
+}
+
+// Check if the field was changed during the turn
+func (ws *workspace) changed() bool {
+	return ws.lastStep.nextStep != nil
+}
+
+// Seconds left before the turn's deadline
+func (ws *workspace) timeLeft(now time.Time) int {
+	left := ws.deadline.Sub(now)
+	if left <= 0 {
+		return 0
+	}
+
+	return int((left + time.Second - 1) / time.Second)
+}
+
+// Restore the field and the player's hand from the workspace
+func (g *Game) revertTurn() {
+	ws := g.workspace
+	if ws == nil {
+		return
+	}
+
+	for s := ws.lastStep.nextStep; s != nil; s = s.nextStep {
+		delete(g.history.combinations, s)
+	}
+
+	ws.lastStep.nextStep = nil
+	g.history.lastStep = ws.lastStep
+	g.stepNumber = ws.stepNumber
+
+	g.field = ws.field.copy()
+	g.hands[ws.player] = append(hand{}, ws.hand...)
+	ws.retrieved = map[*Piece]bool{}
+}
+
+// Validate the field and the player's hand at the end of the turn
+func (g *Game) commitTurn() error {
+	ws := g.workspace
+
+	initialHand := mapset.NewSet[*Piece](ws.hand...)
+	currentHand := mapset.NewSet[*Piece](g.hands[ws.player]...)
+
+	for p := range currentHand.Iter() {
+		if initialHand.Contains(p) {
+			continue
+		}
+
+		if !ws.retrieved[p] {
+			return NewError(
+				ErrorCodeInvalidTurn,
+				"pieces taken from the field must be returned to it",
+			)
+		}
+
+		if g.rules.StrictJokerRetrieval {
+			return NewError(
+				ErrorCodeJokerRetrieval,
+				"retrieved joker %v must be played in the same turn", p.ID,
+			)
+		}
+	}
+
+	if initialHand.Difference(currentHand).Cardinality() == 0 {
+		return NewError(
+			ErrorCodeInvalidTurn,
+			"at least one piece from the hand must be played",
+		)
+	}
+
+	validated := field{}
+
+	for s, c := range g.field {
+		if _, ok := ws.field[s]; ok {
+			continue
+		}
+
+		// Values the jokers had at the beginning of the turn are
+		// kept if they fit, other declarations must be followed
+		declared := map[string]JokerAssignment{}
+		for id, j := range c.declarations() {
+			if v, ok := ws.jokers[id]; !ok || v != j {
+				declared[id] = j
+			}
+		}
+
+		newCombination := g.rules.preferredCombination(
+			c.Pieces, declared, ws.jokers,
+		)
+		if newCombination == nil {
+			return NewError(
+				ErrorCodeInvalidCombination, "combination %v is invalid", s.number,
+			)
+		}
+
+		validated[s] = newCombination
+	}
+
+	for s, c := range validated {
+		g.field[s] = c
+		g.history.combinations[s] = c
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"log"
+
+	"github.com/eightlay/rummikub-server/iternal/bot"
+	"github.com/eightlay/rummikub-server/iternal/game"
+	"github.com/google/uuid"
+)
+
+// Event AddBot
+type EventAddBot struct {
+	Difficulty bot.Difficulty `json:"difficulty"`
+}
+
+// Bot seated in the room
+//
+// Plays in its own goroutine: gets the states from the hub
+// and sends the events back like a client does
+type botSeat struct {
+	hub    *Hub
+	id     uuid.UUID
+	player bot.Player
+
+	// Latest state of the game, older ones are dropped
+	states chan *game.State
+
+	// Last event of the turn was rejected
+	failed bool
+}
+
+// Create bot seat
+func newBotSeat(hub *Hub, id uuid.UUID, player bot.Player) *botSeat {
+	return &botSeat{
+		hub:    hub,
+		id:     id,
+		player: player,
+		states: make(chan *game.State, 1),
+	}
+}
+
+// Replace the waiting state with the new one
+//
+// Called by the hub only, so it never blocks
+func (b *botSeat) push(state *game.State) {
+	select {
+	case <-b.states:
+	default:
+	}
+	b.states <- state
+}
+
+// Play until the room is closed
+func (b *botSeat) run() {
+	for {
+		select {
+		case state := <-b.states:
+			b.play(state)
+		case <-b.hub.done:
+			return
+		}
+	}
+}
+
+// Answer the state
+//
+// If the bot's event is rejected, the turn is finished without playing
+func (b *botSeat) play(state *game.State) {
+	if !state.Turn {
+		b.failed = false
+		return
+	}
+
+	var e *game.Event
+	if b.failed {
+		e = bot.Fallback(state)
+	} else {
+		e = b.player.Play(state)
+	}
+
+	for e != nil {
+		r := &hubRequest{player: b.id.String(), event: e}
+		if err := b.hub.request(b.hub.botEvents, r); err != nil {
+			return
+		}
+
+		err := r.response.Err()
+		if err == nil || b.failed {
+			return
+		}
+
+		log.Printf("bot %v event %v is rejected: %v", b.id, e.Type, err)
+		b.failed = true
+		e = bot.Fallback(state)
+	}
+}
+
+// Seat the bot in the game on the host's request
+func (h *Hub) addBot(client *Client, d bot.Difficulty) error {
+	if h.host == nil || h.host != client {
+		return game.NewError(ErrorCodeForbidden, "only the host can add bots")
+	}
+
+	if h.game.IsStarted() {
+		return game.NewError(
+			ErrorCodeAlreadyStarted,
+			"game in room %v is already started", h.code,
+		)
+	}
+
+	if h.players() >= h.capacity {
+		return game.NewError(ErrorCodeRoomFull, "room %v is full", h.code)
+	}
+
+	player, err := bot.New(d)
+	if err != nil {
+		return game.NewError(game.ErrorCodeInvalidData, "%v", err)
+	}
+
+	id := uuid.New()
+	if err := h.game.AddPlayer(id.String()).Err(); err != nil {
+		return err
+	}
+
+	// Bots are always ready
+	ready := h.game.HandleEvent(id.String(), game.NewEvent(game.EventTypeReady, nil))
+	if err := ready.Err(); err != nil {
+		h.game.RemovePlayer(id.String())
+		return err
+	}
+
+	seat := newBotSeat(h, id, player)
+	h.bots[id] = seat
+	go seat.run()
+
+	h.sendStates()
+
+	return nil
+}
+
+// Handle the bot's game event and send the states on success
+func (h *Hub) handleBotEvent(player string, e *game.Event) *game.Event {
+	response := h.game.HandleEvent(player, e)
+
+	if response.Type != game.EventTypeError {
+		h.sendStates()
+	}
+
+	return response
+}
@@ -0,0 +1,149 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eightlay/rummikub-server/iternal/game"
+)
+
+const (
+	// Maximal length (in characters) of a chat message
+	maxChatLength = 200
+
+	// Number of chat messages a client can send at once
+	chatBurst = 5
+
+	// Period of restoring one chat message to the client's limit
+	chatRefillPeriod = 2 * time.Second
+
+	// Chat channel of the players
+	playersChannel = "players"
+
+	// Chat channel of the spectators
+	spectatorsChannel = "spectators"
+)
+
+// Emotes which can be sent to the chat
+var emotesSet map[string]bool = map[string]bool{
+	"thumbsUp": true,
+	"laugh":    true,
+	"think":    true,
+	"wow":      true,
+	"sad":      true,
+	"angry":    true,
+}
+
+// Chat filter
+//
+// Checks a chat message before it is sent to the room. Can change
+// the text (e.g. mask profanity) or reject the message (e.g. for a
+// muted client) by returning an error
+type ChatFilter interface {
+	Filter(room string, sender string, text string) (string, error)
+}
+
+// Event Chat as sent by the client
+type EventChat struct {
+	Text  string `json:"text"`
+	Emote string `json:"emote"`
+}
+
+// Chat message waiting to be routed by the hub
+type chatMessage struct {
+	client *Client
+	text   string
+	emote  string
+}
+
+// Chat rate limiter
+//
+// Allows chatBurst messages at once and restores
+// one message every chatRefillPeriod
+type chatLimiter struct {
+	tokens  int
+	updated time.Time
+}
+
+// Check if one more message can be sent now
+func (l *chatLimiter) allow(now time.Time) bool {
+	if l.updated.IsZero() {
+		l.tokens = chatBurst
+		l.updated = now
+	}
+
+	refilled := int(now.Sub(l.updated) / chatRefillPeriod)
+	if refilled > 0 {
+		l.tokens += refilled
+		l.updated = l.updated.Add(time.Duration(refilled) * chatRefillPeriod)
+	}
+
+	if l.tokens > chatBurst {
+		l.tokens = chatBurst
+	}
+
+	if l.tokens == 0 {
+		return false
+	}
+
+	l.tokens -= 1
+	return true
+}
+
+// Check the chat message and send it to the hub
+func (c *Client) handleChat(e *game.Event) *game.Event {
+	var ec EventChat
+	if err := e.DecodeData(&ec); err != nil {
+		return errorEvent(err)
+	}
+
+	text := strings.TrimSpace(ec.Text)
+
+	if ec.Emote != "" {
+		if !emotesSet[ec.Emote] {
+			return errorEvent(game.NewError(
+				ErrorCodeChatRejected, "there is no emote %v", ec.Emote,
+			))
+		}
+	} else if text == "" {
+		return errorEvent(game.NewError(
+			ErrorCodeChatRejected, "chat message is empty",
+		))
+	}
+
+	if utf8.RuneCountInString(text) > maxChatLength {
+		return errorEvent(game.NewError(
+			ErrorCodeChatRejected,
+			"chat message is longer than %v characters", maxChatLength,
+		))
+	}
+
+	if !c.chatLimiter.allow(time.Now()) {
+		return errorEvent(game.NewError(
+			ErrorCodeRateLimited, "too many chat messages",
+		))
+	}
+
+	for _, f := range c.manager.chatFilters {
+		var err error
+		text, err = f.Filter(c.hub.code, c.player, text)
+		if err != nil {
+			return errorEvent(game.NewError(ErrorCodeChatRejected, "%v", err))
+		}
+	}
+
+	select {
+	case c.hub.chat <- &chatMessage{c, text, ec.Emote}:
+	case <-c.hub.done:
+		return errorEvent(game.NewError(
+			ErrorCodeRoomClosed, "room %v is closed", c.hub.code,
+		))
+	}
+
+	return &game.Event{Type: game.EventTypeSuccess}
+}
@@ -10,7 +10,6 @@ package server
 
 import (
 	"bytes"
-	"encoding/json"
 	"log"
 	"net/http"
 	"time"
@@ -30,7 +29,7 @@ const (
 	pingPeriod = (pongWait * 9) / 10
 
 	// Maximum message size allowed from peer.
-	maxMessageSize = 512
+	maxMessageSize = 2048
 )
 
 var (
@@ -45,8 +44,20 @@ var upgrader = websocket.Upgrader{
 
 // Client is a middleman between the websocket connection and the hub.
 type Client struct {
+	manager *Manager
+
+	// Current room, nil while the client is in the lobby.
 	hub *Hub
 
+	// Player's id in the current room's game, set by the hub.
+	player string
+
+	// Client watches the game in the current room.
+	spectator bool
+
+	// Limits the rate of the client's chat messages.
+	chatLimiter chatLimiter
+
 	// The websocket connection.
 	conn *websocket.Conn
 
@@ -59,9 +70,15 @@ type Client struct {
 // The application runs readPump in a per-connection goroutine. The application
 // ensures that there is at most one reader on a connection by executing all
 // reads from this goroutine.
-func (c *Client) readPump(g *game.Game) {
+func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		if c.hub != nil {
+			select {
+			case c.hub.unregister <- c:
+			case <-c.hub.done:
+			}
+		}
+		close(c.send)
 		c.conn.Close()
 	}()
 	c.conn.SetReadLimit(maxMessageSize)
@@ -77,12 +94,62 @@ func (c *Client) readPump(g *game.Game) {
 		}
 		message = bytes.TrimSpace(bytes.Replace(message, newline, space, -1))
 
-		var event game.Event
-		json.Unmarshal(message, &event)
-		response := g.HandleEvent(&event)
-		c.conn.WriteJSON(response)
+		request, err := decodeRequest(message)
+		if err != nil {
+			requestID := ""
+			if request != nil {
+				requestID = request.RequestID
+			}
+			c.reply(requestID, errorEvent(err))
+			continue
+		}
 
-		c.hub.broadcast <- []byte{}
+		c.reply(request.RequestID, c.handleEvent(request.Event))
+	}
+}
+
+// Handle event from the client
+func (c *Client) handleEvent(event *game.Event) *game.Event {
+	if lobbyEventsSet[event.Type] {
+		return c.handleLobbyEvent(event)
+	}
+
+	if c.hub == nil {
+		return errorEvent(game.NewError(ErrorCodeNotInRoom, "join a room first"))
+	}
+
+	if event.Type == game.EventTypeChat {
+		return c.handleChat(event)
+	}
+
+	if c.spectator {
+		return errorEvent(game.NewError(
+			ErrorCodeForbidden, "spectators can't play",
+		))
+	}
+
+	r := &hubRequest{client: c, event: event}
+	if err := c.hub.request(c.hub.events, r); err != nil {
+		return errorEvent(err)
+	}
+
+	return r.response
+}
+
+// Send response to the client's request
+func (c *Client) reply(requestID string, e *game.Event) {
+	c.sendOrDisconnect(responseMessage(requestID, e))
+}
+
+// Queue the message the client can't do without
+//
+// The client is disconnected if it is not keeping up,
+// so the message is never lost silently
+func (c *Client) sendOrDisconnect(message []byte) {
+	select {
+	case c.send <- message:
+	default:
+		c.conn.Close()
 	}
 }
 
@@ -107,20 +174,8 @@ func (c *Client) writePump() {
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
-			}
-			w.Write(message)
-
-			// Add queued chat messages to the current websocket message.
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write(newline)
-				w.Write(<-c.send)
-			}
-
-			if err := w.Close(); err != nil {
+			// Every message is written as a separate websocket message.
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
 				return
 			}
 		case <-ticker.C:
@@ -133,19 +188,42 @@ func (c *Client) writePump() {
 }
 
 // serveWs handles websocket requests from the peer.
+//
+// The client joins the room given by the "room" query parameter
+// or stays in the lobby if there is no such parameter. With the "token"
+// query parameter the client reconnects to his seat in the room, with
+// the "spectate" query parameter the client watches the game ("reveal"
+// value shows delayed hands to the commentator with the host's invite
+// token).
 func serveWs(m *Manager, w http.ResponseWriter, r *http.Request) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println(err)
 		return
 	}
-	hub := m.getHub()
-	client := &Client{hub: hub, conn: conn, send: make(chan []byte, 256)}
-	hub.register <- client
-	hub.broadcast <- []byte{}
+	client := &Client{manager: m, conn: conn, send: make(chan []byte, 256)}
 
 	// Allow collection of memory referenced by the caller by doing all work in
 	// new goroutines.
 	go client.writePump()
-	go client.readPump(hub.game)
+
+	if code := r.URL.Query().Get("room"); code != "" {
+		var err error
+		token := r.URL.Query().Get("token")
+		if mode, ok := r.URL.Query()["spectate"]; ok {
+			_, err = client.spectateRoom(code, mode[0] == "reveal", token)
+		} else if token != "" {
+			_, err = client.rejoinRoom(code, token)
+		} else {
+			_, err = client.joinRoom(code)
+		}
+
+		if err != nil {
+			client.send <- pushMessage(errorEvent(err))
+			close(client.send)
+			return
+		}
+	}
+
+	go client.readPump()
 }
@@ -0,0 +1,60 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"time"
+
+	"github.com/eightlay/rummikub-server/iternal/game"
+	"github.com/eightlay/rummikub-server/iternal/solver"
+)
+
+// Time budget of the solver looking for a hint
+const hintBudget = time.Second
+
+// Hint computed for the client's request
+type hintAnswer struct {
+	request *hubRequest
+	query   *game.HintQuery
+	hint    *game.Hint
+}
+
+// Look for the hint requested by the client
+//
+// The solver runs in its own goroutine like bots do, so the room
+// isn't blocked. The request is answered when the hub gets the hint
+func (h *Hub) queryHint(r *hubRequest) {
+	id, ok := h.clients[r.client]
+	if !ok {
+		r.response = errorEvent(game.NewError(
+			ErrorCodeNotInRoom, "client is not a player in room %v", h.code,
+		))
+		r.result <- nil
+		return
+	}
+
+	q, err := h.game.QueryHint(id.String(), r.event)
+	if err != nil {
+		r.response = errorEvent(err)
+		r.result <- nil
+		return
+	}
+
+	go func() {
+		hint := q.Hint(solver.Hinter{Options: solver.Options{Budget: hintBudget}})
+
+		select {
+		case h.hints <- &hintAnswer{request: r, query: q, hint: hint}:
+		case <-h.done:
+			r.result <- game.NewError(ErrorCodeRoomClosed, "room %v is closed", h.code)
+		}
+	}()
+}
+
+// Charge the player for the hint and answer the request
+func (h *Hub) answerHint(a *hintAnswer) {
+	a.request.response = h.game.AnswerHint(a.query, a.hint)
+	a.request.result <- nil
+}
@@ -9,86 +9,659 @@
 package server
 
 import (
-	"encoding/json"
+	"log"
+	"sync"
+	"time"
 
+	"github.com/eightlay/rummikub-server/iternal/bot"
 	"github.com/eightlay/rummikub-server/iternal/game"
 	"github.com/google/uuid"
 )
 
+const (
+	// Period of checking the current turn's time limit
+	turnTimerPeriod = time.Second
+
+	// Time a disconnected player's seat is held for reconnection
+	reconnectGracePeriod = 60 * time.Second
+
+	// Time a room is kept open waiting for the first client
+	emptyRoomTimeout = 5 * time.Minute
+
+	// Delay of the full reveal states sent to commentators
+	revealDelay = 2 * time.Minute
+)
+
 // Hub maintains the set of active clients and broadcasts messages to the
 // clients.
+//
+// The hub's run loop is the only owner of the game, every access
+// to the game goes through the hub's channels.
 type Hub struct {
 	// Pointer to manager
 	manager *Manager
 
+	// Room code
+	code string
+
+	// Room name
+	name string
+
+	// Maximal number of players in the room
+	capacity int
+
+	// Time the room was created
+	created time.Time
+
+	// Client who can start the game
+	host *Client
+
 	// Registered clients.
 	clients map[*Client]uuid.UUID
 
+	// Bots seated in the game.
+	bots map[uuid.UUID]*botSeat
+
+	// Spectators, true for commentators who see delayed hands.
+	spectators map[*Client]bool
+
+	// Commentator invites issued by the host, by their tokens.
+	invites map[string]bool
+
+	// Full reveal states waiting to be sent to commentators.
+	revealQueue []delayedState
+
+	// Players by their session tokens.
+	tokens map[string]uuid.UUID
+
+	// Disconnected players and deadlines for their reconnection.
+	disconnected map[uuid.UUID]time.Time
+
 	// Game
 	game *game.Game
 
-	// Inbound messages from the clients.
-	broadcast chan []byte
+	// Room information published by the run loop.
+	roomInfo RoomInfo
+
+	// Game phase last announced to the room.
+	phase game.Phase
+
+	// Guards the room information.
+	infoMu sync.RWMutex
+
+	// Game events from the clients.
+	events chan *hubRequest
+
+	// Hints computed for the clients.
+	hints chan *hintAnswer
+
+	// Game events from the bots.
+	botEvents chan *hubRequest
+
+	// Requests to seat a bot.
+	addBots chan *hubRequest
 
 	// Register requests from the clients.
-	register chan *Client
+	register chan *hubRequest
 
-	// Unregister requests from clients.
+	// Unregister requests from clients which lost the connection.
 	unregister chan *Client
+
+	// Requests from clients which leave the room.
+	leave chan *Client
+
+	// Reconnect requests from the clients.
+	reconnect chan *hubRequest
+
+	// Spectate requests from the clients.
+	spectate chan *hubRequest
+
+	// Commentator invite requests.
+	invite chan *hubRequest
+
+	// Chat messages from the clients.
+	chat chan *chatMessage
+
+	// Start requests.
+	start chan *hubRequest
+
+	// Closed when the hub stops running.
+	done chan struct{}
 }
 
-func newHub(manager *Manager) *Hub {
-	return &Hub{
-		broadcast:  make(chan []byte),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		clients:    make(map[*Client]uuid.UUID),
-		game:       game.NewGame(),
-		manager:    manager,
+// Request to the hub
+//
+// The hub sends the result of handling the request to the result channel
+type hubRequest struct {
+	client     *Client
+	player     string
+	token      string
+	reveal     bool
+	event      *game.Event
+	difficulty bot.Difficulty
+	result     chan error
+
+	// Response to the event, set before the result is sent
+	response *game.Event
+}
+
+// State to be sent after the delay
+type delayedState struct {
+	time  time.Time
+	state []byte
+}
+
+func newHub(manager *Manager, code string, settings RoomSettings) (*Hub, error) {
+	g, err := game.NewGame(settings.Rules, time.Now().UnixNano())
+	if err != nil {
+		return nil, err
 	}
+	log.Printf("game created with seed %v", g.Seed())
+
+	h := &Hub{
+		code:         code,
+		name:         settings.Name,
+		capacity:     settings.Capacity,
+		created:      time.Now(),
+		events:       make(chan *hubRequest),
+		hints:        make(chan *hintAnswer),
+		botEvents:    make(chan *hubRequest),
+		addBots:      make(chan *hubRequest),
+		register:     make(chan *hubRequest),
+		unregister:   make(chan *Client),
+		leave:        make(chan *Client),
+		reconnect:    make(chan *hubRequest),
+		spectate:     make(chan *hubRequest),
+		invite:       make(chan *hubRequest),
+		chat:         make(chan *chatMessage),
+		start:        make(chan *hubRequest),
+		done:         make(chan struct{}),
+		clients:      make(map[*Client]uuid.UUID),
+		bots:         make(map[uuid.UUID]*botSeat),
+		spectators:   make(map[*Client]bool),
+		invites:      make(map[string]bool),
+		tokens:       make(map[string]uuid.UUID),
+		disconnected: make(map[uuid.UUID]time.Time),
+		game:         g,
+		manager:      manager,
+		phase:        g.Phase(),
+	}
+	h.publishInfo()
+
+	return h, nil
 }
 
 func (h *Hub) run() {
+	ticker := time.NewTicker(turnTimerPeriod)
+	defer ticker.Stop()
+	defer close(h.done)
+
+	// Disconnect spectators when the room is closed
+	defer func() {
+		for client := range h.spectators {
+			client.conn.Close()
+		}
+	}()
+
 	for {
+		h.announcePhase()
+		h.publishInfo()
+
 		select {
-		case client := <-h.register:
-			h.clients[client] = uuid.New()
-			h.game.AddPlayer(h.clients[client].String())
-			client.conn.WriteJSON(game.Event{
-				Type: game.EventTypeInit,
-				Data: game.EventInit{
-					Player: h.clients[client].String(),
-				},
-			})
+		case r := <-h.register:
+			r.result <- h.registerClient(r.client)
+		case r := <-h.reconnect:
+			r.result <- h.reconnectClient(r.client, r.token)
+		case r := <-h.spectate:
+			r.result <- h.addSpectator(r.client, r.reveal, r.token)
+		case r := <-h.invite:
+			r.result <- h.inviteCommentator(r.client, r.token)
 		case client := <-h.unregister:
-			if _, ok := h.clients[client]; ok {
-				h.game.RemovePlayer(h.clients[client].String())
+			delete(h.spectators, client)
+			if id, ok := h.clients[client]; ok {
+				if h.game.IsStarted() {
+					// Hold the seat, so the player can reconnect
+					h.disconnected[id] = time.Now().Add(reconnectGracePeriod)
+				} else {
+					h.removePlayer(id)
+				}
+				h.removeClient(client)
+			}
+			if h.empty() {
+				h.sendRemoveHub()
+				return
+			}
+		case client := <-h.leave:
+			delete(h.spectators, client)
+			if id, ok := h.clients[client]; ok {
+				h.removePlayer(id)
+				h.removeClient(client)
+			}
+			if h.empty() {
+				h.sendRemoveHub()
+				return
+			}
+		case r := <-h.start:
+			id := h.tokens[r.token]
+			if r.client != nil {
+				id = h.clients[r.client]
+			}
+			r.result <- h.startGame(id)
+		case m := <-h.chat:
+			h.routeChat(m)
+		case r := <-h.events:
+			if r.event.Type == game.EventTypeHint {
+				h.queryHint(r)
+				break
+			}
+			r.response = h.handleEvent(r.client, r.event)
+			r.result <- nil
+		case a := <-h.hints:
+			h.answerHint(a)
+		case r := <-h.botEvents:
+			r.response = h.handleBotEvent(r.player, r.event)
+			r.result <- nil
+		case r := <-h.addBots:
+			r.result <- h.addBot(r.client, r.difficulty)
+		case now := <-ticker.C:
+			changed := h.game.CheckCountdown()
+
+			if h.game.CheckTimeout() {
+				changed = true
+			}
 
-				message, _ := json.Marshal(game.Event{
-					Type: game.EventTypeDisconnect,
-				})
+			if h.skipDisconnectedTurn() {
+				changed = true
+			}
 
-				client.conn.WriteJSON(message)
-				h.broadcast <- message
+			h.sendDelayedStates(now)
 
-				delete(h.clients, client)
-				close(client.send)
+			// Nobody joined the room in time
+			if h.empty() && now.Sub(h.created) >= emptyRoomTimeout {
+				h.sendRemoveHub()
+				return
+			}
 
-				if len(h.clients) == 0 {
+			if h.removeExpiredSeats(now) {
+				if h.empty() {
 					h.sendRemoveHub()
+					return
 				}
+				changed = true
 			}
-		case <-h.broadcast:
-			for client, cid := range h.clients {
-				select {
-				case client.send <- h.game.State(cid.String()).ToJSON():
-				default:
-					close(client.send)
-					delete(h.clients, client)
-				}
+
+			if changed {
+				h.sendStates()
+			}
+		}
+	}
+}
+
+// Check if there are no clients and no held seats in the room
+//
+// Bots don't keep the room open
+func (h *Hub) empty() bool {
+	return len(h.clients) == 0 && len(h.disconnected) == 0
+}
+
+// Number of clients and bots seated in the room
+func (h *Hub) players() int {
+	return len(h.clients) + len(h.bots)
+}
+
+// Remove client from the room and notify other clients
+func (h *Hub) removeClient(client *Client) {
+	delete(h.clients, client)
+
+	if h.host == client {
+		h.host = nil
+		for c := range h.clients {
+			h.host = c
+			break
+		}
+	}
+
+	message := pushMessage(&game.Event{
+		Type: game.EventTypeDisconnect,
+	})
+	h.sendToAll(message)
+	h.sendToSpectators(message)
+	h.sendStates()
+}
+
+// Remove player from the game and forget his session
+func (h *Hub) removePlayer(id uuid.UUID) {
+	h.game.RemovePlayer(id.String())
+	delete(h.disconnected, id)
+
+	for token, player := range h.tokens {
+		if player == id {
+			delete(h.tokens, token)
+		}
+	}
+}
+
+// Skip the turn if it belongs to a disconnected player
+func (h *Hub) skipDisconnectedTurn() bool {
+	current := h.game.CurrentPlayer()
+	if current == "" {
+		return false
+	}
+
+	id, err := uuid.Parse(current)
+	if err != nil {
+		return false
+	}
+
+	if _, ok := h.disconnected[id]; !ok {
+		return false
+	}
+
+	return h.game.SkipTurn() == nil
+}
+
+// Remove players which didn't reconnect in time
+func (h *Hub) removeExpiredSeats(now time.Time) bool {
+	removed := false
+
+	for id, deadline := range h.disconnected {
+		if now.After(deadline) {
+			h.removePlayer(id)
+			removed = true
+		}
+	}
+
+	return removed
+}
+
+// Bind the client to the seat of the player with the given session token
+func (h *Hub) reconnectClient(client *Client, token string) error {
+	id, ok := h.tokens[token]
+	if !ok {
+		return game.NewError(ErrorCodeInvalidToken, "invalid session token")
+	}
+
+	// Drop the previous connection of the player if it is still alive
+	for c, cid := range h.clients {
+		if cid == id {
+			delete(h.clients, c)
+			if h.host == c {
+				h.host = client
 			}
+			c.conn.Close()
 		}
 	}
+
+	delete(h.disconnected, id)
+	h.clients[client] = id
+	client.player = id.String()
+	if h.host == nil {
+		h.host = client
+	}
+
+	h.sendInit(client, id, token)
+	h.sendStates()
+
+	return nil
+}
+
+// Send player's id and session token to the client
+//
+// Never blocks the hub, the client is disconnected if it is not keeping up
+func (h *Hub) sendInit(client *Client, id uuid.UUID, token string) {
+	message := pushMessage(game.NewEvent(game.EventTypeInit, game.EventInit{
+		Player: id.String(),
+		Token:  token,
+	}))
+	client.sendOrDisconnect(message)
+}
+
+// Send request to the hub and wait for the result
+func (h *Hub) request(requests chan *hubRequest, r *hubRequest) error {
+	r.result = make(chan error, 1)
+
+	select {
+	case requests <- r:
+		return <-r.result
+	case <-h.done:
+		return game.NewError(ErrorCodeRoomClosed, "room %v is closed", h.code)
+	}
+}
+
+// Add client to the room and seat him in the game
+func (h *Hub) registerClient(client *Client) error {
+	if h.game.IsStarted() {
+		return game.NewError(
+			ErrorCodeAlreadyStarted,
+			"game in room %v is already started", h.code,
+		)
+	}
+
+	if h.players() >= h.capacity {
+		return game.NewError(ErrorCodeRoomFull, "room %v is full", h.code)
+	}
+
+	id := uuid.New()
+	if err := h.game.AddPlayer(id.String()).Err(); err != nil {
+		return err
+	}
+
+	token := uuid.New().String()
+	h.tokens[token] = id
+
+	h.clients[client] = id
+	client.player = id.String()
+	if h.host == nil {
+		h.host = client
+	}
+
+	h.sendInit(client, id, token)
+	h.sendStates()
+
+	return nil
+}
+
+// Add spectator to the room
+//
+// Commentators who see the delayed hands need the host's invite,
+// every invite is used once
+func (h *Hub) addSpectator(client *Client, reveal bool, token string) error {
+	if reveal {
+		if !h.invites[token] {
+			return game.NewError(
+				ErrorCodeInvalidToken, "commentators need the host's invite",
+			)
+		}
+		delete(h.invites, token)
+	}
+
+	h.spectators[client] = reveal
+	h.sendStates()
+
+	return nil
+}
+
+// Accept the commentator invite token on the host's request
+func (h *Hub) inviteCommentator(client *Client, token string) error {
+	if h.host == nil || h.host != client {
+		return game.NewError(
+			ErrorCodeForbidden, "only the host can invite commentators",
+		)
+	}
+
+	h.invites[token] = true
+
+	return nil
+}
+
+// Start the game on the host's request
+func (h *Hub) startGame(id uuid.UUID) error {
+	if h.host == nil || h.clients[h.host] != id {
+		return game.NewError(
+			ErrorCodeForbidden, "only the host can start the game",
+		)
+	}
+
+	if err := h.game.Start(); err != nil {
+		return err
+	}
+	h.sendStates()
+
+	return nil
+}
+
+// Handle the client's game event and send the states on success
+func (h *Hub) handleEvent(client *Client, e *game.Event) *game.Event {
+	id, ok := h.clients[client]
+	if !ok {
+		return errorEvent(game.NewError(
+			ErrorCodeNotInRoom, "client is not a player in room %v", h.code,
+		))
+	}
+
+	response := h.game.HandleEvent(id.String(), e)
+
+	if response.Type != game.EventTypeError {
+		h.sendStates()
+	}
+
+	return response
+}
+
+// Notify the room if the game moved to another phase
+func (h *Hub) announcePhase() {
+	phase := h.game.Phase()
+	if phase == h.phase {
+		return
+	}
+	h.phase = phase
+
+	message := pushMessage(game.NewEvent(game.EventTypePhase, game.EventPhase{
+		Phase:     phase,
+		Countdown: h.game.CountdownLeft(),
+	}))
+	h.sendToAll(message)
+	h.sendToSpectators(message)
+}
+
+// Room information
+//
+// Safe to call from any goroutine
+func (h *Hub) info() RoomInfo {
+	h.infoMu.RLock()
+	defer h.infoMu.RUnlock()
+
+	return h.roomInfo
+}
+
+// Update the room information seen by other goroutines
+func (h *Hub) publishInfo() {
+	h.infoMu.Lock()
+	defer h.infoMu.Unlock()
+
+	h.roomInfo = RoomInfo{
+		Code:     h.code,
+		Name:     h.name,
+		Capacity: h.capacity,
+		Players:  h.players(),
+		Phase:    h.game.Phase(),
+		Started:  h.game.IsStarted(),
+		Rules:    h.game.Rules(),
+	}
+}
+
+// Send message to every client
+func (h *Hub) sendToAll(message []byte) {
+	for client := range h.clients {
+		client.sendOrDisconnect(message)
+	}
+}
+
+// Send chat message to the sender's channel
+//
+// Players' messages are seen by everyone in the room,
+// spectators' messages are seen by spectators only
+func (h *Hub) routeChat(m *chatMessage) {
+	channel := playersChannel
+	if _, ok := h.spectators[m.client]; ok {
+		channel = spectatorsChannel
+	} else if _, ok := h.clients[m.client]; !ok {
+		return
+	}
+
+	event := h.game.RecordChat(m.client.player, channel, m.text, m.emote)
+	message := pushMessage(event)
+
+	if channel == playersChannel {
+		h.sendToAll(message)
+	}
+	h.sendToSpectators(message)
+}
+
+// Send message to every spectator
+func (h *Hub) sendToSpectators(message []byte) {
+	for client := range h.spectators {
+		client.sendOrDisconnect(message)
+	}
+}
+
+// Send game state to every client
+//
+// Clients which are not keeping up are disconnected,
+// so they never miss a state silently. Commentators get
+// the live state too, the revealed one comes after the delay
+func (h *Hub) sendStates() {
+	for client, cid := range h.clients {
+		client.sendOrDisconnect(statePush(h.game.State(cid.String())))
+	}
+
+	for id, seat := range h.bots {
+		seat.push(h.game.State(id.String()))
+	}
+
+	if len(h.spectators) == 0 {
+		return
+	}
+
+	finished := h.game.IsFinished()
+	state := statePush(h.game.SpectatorState(false))
+	commentators := false
+
+	for client, reveal := range h.spectators {
+		if reveal && !finished {
+			commentators = true
+		}
+
+		client.sendOrDisconnect(state)
+	}
+
+	if commentators {
+		revealed := statePush(h.game.SpectatorState(true))
+		h.revealQueue = append(h.revealQueue, delayedState{time.Now(), revealed})
+	}
+}
+
+// Send full reveal states to commentators after the delay
+func (h *Hub) sendDelayedStates(now time.Time) {
+	due := 0
+	for due < len(h.revealQueue) && now.Sub(h.revealQueue[due].time) >= revealDelay {
+		due += 1
+	}
+
+	if due == 0 {
+		return
+	}
+
+	state := h.revealQueue[due-1].state
+	h.revealQueue = h.revealQueue[due:]
+
+	for client, reveal := range h.spectators {
+		if !reveal {
+			continue
+		}
+
+		client.sendOrDisconnect(state)
+	}
 }
 
 func (h *Hub) sendRemoveHub() {
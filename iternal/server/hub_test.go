@@ -0,0 +1,251 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eightlay/rummikub-server/iternal/game"
+	"github.com/gorilla/websocket"
+)
+
+// Time the test client waits for a response
+const testResponseTimeout = 10 * time.Second
+
+// Client connected to the test server
+type testClient struct {
+	conn *websocket.Conn
+
+	mu        sync.Mutex
+	requests  int
+	responses map[string]chan *game.Event
+	state     *game.State
+
+	// Closed when the game is started
+	started chan struct{}
+}
+
+// Connect to the room of the test server
+func dialRoom(t *testing.T, srv *httptest.Server, code string) *testClient {
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "?room=" + code
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("client is not connected: %v", err)
+	}
+
+	c := &testClient{
+		conn:      conn,
+		responses: map[string]chan *game.Event{},
+		started:   make(chan struct{}),
+	}
+	go c.read()
+
+	return c
+}
+
+// Dispatch responses and keep the latest state until the connection is closed
+func (c *testClient) read() {
+	started := false
+
+	for {
+		var m Message
+		if err := c.conn.ReadJSON(&m); err != nil {
+			return
+		}
+
+		c.mu.Lock()
+		switch {
+		case m.Kind == KindResponse:
+			if response, ok := c.responses[m.RequestID]; ok {
+				response <- m.Event
+				delete(c.responses, m.RequestID)
+			}
+		case m.Event.Type == EventTypeState:
+			var s game.State
+			if err := m.Event.DecodeData(&s); err == nil {
+				c.state = &s
+				if s.Phase == game.PhaseStarted && !started {
+					started = true
+					close(c.started)
+				}
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+// Send request and wait for the response, nil if there is none
+func (c *testClient) request(t game.EventType, data interface{}) *game.Event {
+	c.mu.Lock()
+	c.requests += 1
+	id := fmt.Sprint(c.requests)
+	response := make(chan *game.Event, 1)
+	c.responses[id] = response
+	c.mu.Unlock()
+
+	raw, _ := json.Marshal(data)
+	message, _ := json.Marshal(Message{
+		Version:   ProtocolVersion,
+		Kind:      KindRequest,
+		RequestID: id,
+		Event:     &game.Event{Type: t, Data: raw},
+	})
+
+	if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+		return nil
+	}
+
+	select {
+	case e := <-response:
+		return e
+	case <-time.After(testResponseTimeout):
+		return nil
+	}
+}
+
+// Latest state received by the client
+func (c *testClient) latestState() *game.State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.state
+}
+
+// Clients and a bot play concurrently while one of the clients drops
+func TestHubConcurrentClients(t *testing.T) {
+	m := newManager(nil)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveWs(m, w, r)
+	}))
+	defer srv.Close()
+
+	settings := defaultRoomSettings()
+	settings.Rules.CountdownSeconds = 1
+
+	hub, err := m.createRoom(settings)
+	if err != nil {
+		t.Fatalf("room is not created: %v", err)
+	}
+
+	clients := []*testClient{}
+	for i := 0; i < 3; i++ {
+		c := dialRoom(t, srv, hub.code)
+		defer c.conn.Close()
+		clients = append(clients, c)
+	}
+
+	// The first client is the host
+	bot := clients[0].request(EventTypeAddBot, EventAddBot{Difficulty: "easy"})
+	if bot == nil || bot.Err() != nil {
+		t.Fatalf("bot is not added: %v", bot)
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Add(1)
+		go func(c *testClient) {
+			defer wg.Done()
+			if e := c.request(game.EventTypeReady, nil); e == nil || e.Err() != nil {
+				t.Errorf("client is not ready: %v", e)
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	for _, c := range clients {
+		select {
+		case <-c.started:
+		case <-time.After(testResponseTimeout):
+			t.Fatalf("game is not started")
+		}
+	}
+
+	// Responses which mean the request is broken or lost
+	broken := map[game.ErrorCode]bool{
+		ErrorCodeInvalidMessage:    true,
+		game.ErrorCodeInvalidData:  true,
+		game.ErrorCodeUnknownEvent: true,
+		ErrorCodeNotInRoom:         true,
+		ErrorCodeRoomClosed:        true,
+	}
+
+	requests := []struct {
+		t    game.EventType
+		data interface{}
+	}{
+		{game.EventTypeReady, nil},
+		{game.EventTypeHint, nil},
+		{game.EventTypeChat, EventChat{Text: "hi"}},
+		{game.EventTypeCommitTurn, nil},
+		{game.EventTypeDraw, nil},
+	}
+
+	dropped := clients[len(clients)-1]
+
+	for i, c := range clients {
+		wg.Add(1)
+		go func(i int, c *testClient) {
+			defer wg.Done()
+
+			for n := 0; n < 30; n++ {
+				if c == dropped && n == 10 {
+					c.conn.Close()
+					return
+				}
+
+				r := requests[(n+i)%len(requests)]
+				e := c.request(r.t, r.data)
+				if e == nil {
+					t.Errorf("client %v got no response to %v", i, r.t)
+					return
+				}
+
+				if err := e.Err(); err != nil && broken[game.ErrorCodeOf(err)] {
+					t.Errorf("client %v got %v to %v", i, err, r.t)
+				}
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	// The room keeps working after the drop
+	for i, c := range clients[:len(clients)-1] {
+		e := c.request(game.EventTypeReady, nil)
+		if e == nil || broken[game.ErrorCodeOf(e.Err())] {
+			t.Fatalf("client %v got %v after the drop", i, e)
+		}
+	}
+
+	// Every piece is in a hand, in the bank or on the field
+	rules := settings.Rules
+	total := rules.DecksNumber * (4*(game.MaxNumber-game.MinNumber+1) + rules.JokersPerDeck)
+
+	for i, c := range clients[:len(clients)-1] {
+		s := c.latestState()
+
+		pieces := len(s.Hand) + s.BankSize
+		for _, o := range s.Opponents {
+			pieces += o.Pieces
+		}
+		for _, f := range s.Field {
+			pieces += len(f.Pieces)
+		}
+
+		if pieces != total {
+			t.Errorf("client %v sees %v pieces, want %v", i, pieces, total)
+		}
+		if len(s.Opponents) != 3 {
+			t.Errorf("client %v sees %v opponents, want 3", i, len(s.Opponents))
+		}
+	}
+}
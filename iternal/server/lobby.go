@@ -0,0 +1,319 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"github.com/eightlay/rummikub-server/iternal/bot"
+	"github.com/eightlay/rummikub-server/iternal/game"
+	"github.com/google/uuid"
+)
+
+const (
+	// List rooms which can be joined
+	EventTypeListRooms game.EventType = "listRooms"
+	// Create new room and join it
+	EventTypeCreateRoom game.EventType = "createRoom"
+	// Join the room by its code
+	EventTypeJoinRoom game.EventType = "joinRoom"
+	// Watch the game in the room by its code
+	EventTypeSpectateRoom game.EventType = "spectateRoom"
+	// Leave the current room
+	EventTypeLeaveRoom game.EventType = "leaveRoom"
+	// Start the game in the current room (host only)
+	EventTypeStartRoom game.EventType = "startRoom"
+	// Seat a bot in the current room (host only)
+	EventTypeAddBot game.EventType = "addBot"
+	// Invite a commentator to the current room (host only)
+	EventTypeInviteCommentator game.EventType = "inviteCommentator"
+)
+
+// Lobby events set
+var lobbyEventsSet map[game.EventType]bool = map[game.EventType]bool{
+	EventTypeListRooms:         true,
+	EventTypeCreateRoom:        true,
+	EventTypeJoinRoom:          true,
+	EventTypeSpectateRoom:      true,
+	EventTypeLeaveRoom:         true,
+	EventTypeStartRoom:         true,
+	EventTypeAddBot:            true,
+	EventTypeInviteCommentator: true,
+}
+
+// Room settings
+//
+// Chosen by the host on the room creation
+type RoomSettings struct {
+	Name     string       `json:"name"`
+	Capacity int          `json:"capacity"`
+	Rules    game.RuleSet `json:"rules"`
+}
+
+// Event SpectateRoom
+//
+// Reveal allows to see players' hands with a delay,
+// it needs the token of the host's commentator invite
+type EventSpectateRoom struct {
+	Code   string `json:"code"`
+	Reveal bool   `json:"reveal"`
+	Token  string `json:"token"`
+}
+
+// Commentator invite
+//
+// The token is used once to spectate the room with reveal
+type CommentatorInvite struct {
+	Token string `json:"token"`
+}
+
+// Event StartRoom
+//
+// Used by the HTTP API only, the host is identified by the session
+// token, websocket clients are identified by their seats
+type EventStartRoom struct {
+	Token string `json:"token"`
+}
+
+// Room information
+type RoomInfo struct {
+	Code     string       `json:"code"`
+	Name     string       `json:"name"`
+	Capacity int          `json:"capacity"`
+	Players  int          `json:"players"`
+	Phase    game.Phase   `json:"phase"`
+	Started  bool         `json:"started"`
+	Rules    game.RuleSet `json:"rules"`
+}
+
+// Event JoinRoom
+type EventJoinRoom struct {
+	Code string `json:"code"`
+}
+
+// Room settings with the default rules and capacity
+func defaultRoomSettings() RoomSettings {
+	rules := game.DefaultRuleSet()
+
+	return RoomSettings{
+		Capacity: rules.MaxPlayersNumber,
+		Rules:    rules,
+	}
+}
+
+// Check if room settings are consistent
+func (s RoomSettings) validate() error {
+	if err := s.Rules.Validate(); err != nil {
+		return game.NewError(ErrorCodeInvalidSettings, "%v", err)
+	}
+
+	if s.Capacity < s.Rules.MinPlayersNumber || s.Capacity > s.Rules.MaxPlayersNumber {
+		return game.NewError(
+			ErrorCodeInvalidSettings,
+			"room capacity must be from %v to %v",
+			s.Rules.MinPlayersNumber, s.Rules.MaxPlayersNumber,
+		)
+	}
+
+	return nil
+}
+
+// Create error event
+func errorEvent(err error) *game.Event {
+	return game.ErrorEvent(err)
+}
+
+// Lobby events handler
+func (c *Client) handleLobbyEvent(e *game.Event) *game.Event {
+	var data interface{}
+	var err error
+
+	switch e.Type {
+	case EventTypeListRooms:
+		data = c.manager.openRooms()
+	case EventTypeCreateRoom:
+		settings := defaultRoomSettings()
+		err = e.DecodeData(&settings)
+		if err == nil {
+			data, err = c.createRoom(settings)
+		}
+	case EventTypeJoinRoom:
+		var ej EventJoinRoom
+		err = e.DecodeData(&ej)
+		if err == nil {
+			data, err = c.joinRoom(ej.Code)
+		}
+	case EventTypeSpectateRoom:
+		var es EventSpectateRoom
+		err = e.DecodeData(&es)
+		if err == nil {
+			data, err = c.spectateRoom(es.Code, es.Reveal, es.Token)
+		}
+	case EventTypeLeaveRoom:
+		err = c.leaveRoom()
+	case EventTypeStartRoom:
+		err = c.startRoom()
+	case EventTypeAddBot:
+		ea := EventAddBot{Difficulty: bot.DifficultyMedium}
+		err = e.DecodeData(&ea)
+		if err == nil {
+			data, err = c.addBot(ea.Difficulty)
+		}
+	case EventTypeInviteCommentator:
+		data, err = c.inviteCommentator()
+	}
+
+	if err != nil {
+		return errorEvent(err)
+	}
+
+	return game.NewEvent(game.EventTypeSuccess, data)
+}
+
+// Create new room and join it
+func (c *Client) createRoom(settings RoomSettings) (*RoomInfo, error) {
+	if c.hub != nil {
+		return nil, game.NewError(
+			ErrorCodeAlreadyInRoom, "leave the current room first",
+		)
+	}
+
+	hub, err := c.manager.createRoom(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.joinRoom(hub.code)
+}
+
+// Join the room by its code
+func (c *Client) joinRoom(code string) (*RoomInfo, error) {
+	if c.hub != nil {
+		return nil, game.NewError(
+			ErrorCodeAlreadyInRoom, "leave the current room first",
+		)
+	}
+
+	hub, err := c.manager.hubByCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := hub.request(hub.register, &hubRequest{client: c}); err != nil {
+		return nil, err
+	}
+
+	c.hub = hub
+
+	info := hub.info()
+	return &info, nil
+}
+
+// Rejoin the room on the seat bound to the session token
+func (c *Client) rejoinRoom(code string, token string) (*RoomInfo, error) {
+	if c.hub != nil {
+		return nil, game.NewError(
+			ErrorCodeAlreadyInRoom, "leave the current room first",
+		)
+	}
+
+	hub, err := c.manager.hubByCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &hubRequest{client: c, token: token}
+	if err := hub.request(hub.reconnect, r); err != nil {
+		return nil, err
+	}
+
+	c.hub = hub
+
+	info := hub.info()
+	return &info, nil
+}
+
+// Watch the game in the room by its code
+func (c *Client) spectateRoom(code string, reveal bool, token string) (*RoomInfo, error) {
+	if c.hub != nil {
+		return nil, game.NewError(
+			ErrorCodeAlreadyInRoom, "leave the current room first",
+		)
+	}
+
+	hub, err := c.manager.hubByCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &hubRequest{client: c, reveal: reveal, token: token}
+	if err := hub.request(hub.spectate, r); err != nil {
+		return nil, err
+	}
+
+	c.hub = hub
+	c.spectator = true
+
+	info := hub.info()
+	return &info, nil
+}
+
+// Leave the current room
+func (c *Client) leaveRoom() error {
+	if c.hub == nil {
+		return game.NewError(ErrorCodeNotInRoom, "client is not in a room")
+	}
+
+	select {
+	case c.hub.leave <- c:
+	case <-c.hub.done:
+	}
+	c.hub = nil
+	c.player = ""
+	c.spectator = false
+
+	return nil
+}
+
+// Start the game in the current room
+func (c *Client) startRoom() error {
+	if c.hub == nil || c.spectator {
+		return game.NewError(
+			ErrorCodeNotInRoom, "client is not a player in a room",
+		)
+	}
+
+	return c.hub.request(c.hub.start, &hubRequest{client: c})
+}
+
+// Seat a bot in the current room
+func (c *Client) addBot(d bot.Difficulty) (*RoomInfo, error) {
+	if c.hub == nil || c.spectator {
+		return nil, game.NewError(
+			ErrorCodeNotInRoom, "client is not a player in a room",
+		)
+	}
+
+	if err := c.hub.request(c.hub.addBots, &hubRequest{client: c, difficulty: d}); err != nil {
+		return nil, err
+	}
+
+	info := c.hub.info()
+	return &info, nil
+}
+
+// Invite a commentator to the current room
+func (c *Client) inviteCommentator() (*CommentatorInvite, error) {
+	if c.hub == nil || c.spectator {
+		return nil, game.NewError(
+			ErrorCodeNotInRoom, "client is not a player in a room",
+		)
+	}
+
+	invite := &CommentatorInvite{Token: uuid.New().String()}
+	if err := c.hub.request(c.hub.invite, &hubRequest{client: c, token: invite.Token}); err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
@@ -4,32 +4,106 @@
 
 package server
 
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/eightlay/rummikub-server/iternal/game"
+	"github.com/google/uuid"
+)
+
+// Length of a room code
+const roomCodeLength = 6
+
 // Hub manager
 type Manager struct {
-	hubs map[*Hub]bool
+	mu   sync.Mutex
+	hubs map[string]*Hub
+
+	// Filters applied to every chat message in order
+	chatFilters []ChatFilter
 }
 
 // Create new hub manager
-func newManager() *Manager {
+func newManager(chatFilters []ChatFilter) *Manager {
 	return &Manager{
-		hubs: map[*Hub]bool{},
+		hubs:        map[string]*Hub{},
+		chatFilters: chatFilters,
 	}
 }
 
-// Get open hub or create new one
-func (m *Manager) getHub() *Hub {
-	for hub := range m.hubs {
-		if !hub.game.IsStarted() {
-			return hub
-		}
+// Create new room
+func (m *Manager) createRoom(settings RoomSettings) (*Hub, error) {
+	if err := settings.validate(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	code := newRoomCode()
+	for _, ok := m.hubs[code]; ok; _, ok = m.hubs[code] {
+		code = newRoomCode()
+	}
+
+	hub, err := newHub(m, code, settings)
+	if err != nil {
+		return nil, err
 	}
-	hub := newHub(m)
-	m.hubs[hub] = true
+
+	m.hubs[code] = hub
 	go hub.run()
-	return hub
+
+	return hub, nil
+}
+
+// Find room by its code
+func (m *Manager) hubByCode(code string) (*Hub, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hub, ok := m.hubs[strings.ToUpper(code)]
+	if !ok {
+		return nil, game.NewError(
+			ErrorCodeRoomNotFound, "there is no room with code %v", code,
+		)
+	}
+
+	return hub, nil
+}
+
+// List rooms which can be joined
+func (m *Manager) openRooms() []RoomInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rooms := []RoomInfo{}
+
+	for _, hub := range m.hubs {
+		info := hub.info()
+		if !info.Started && info.Players < info.Capacity {
+			rooms = append(rooms, info)
+		}
+	}
+
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].Code < rooms[j].Code
+	})
+
+	return rooms
 }
 
 // Remove hub
 func (m *Manager) removeHub(hub *Hub) {
-	delete(m.hubs, hub)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.hubs, hub.code)
+}
+
+// Generate random room code
+func newRoomCode() string {
+	code := strings.ReplaceAll(uuid.New().String(), "-", "")
+	return strings.ToUpper(code[:roomCodeLength])
 }
@@ -0,0 +1,183 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"unicode/utf8"
+
+	"github.com/eightlay/rummikub-server/iternal/game"
+)
+
+// Version of the wire protocol
+const ProtocolVersion = 1
+
+// Maximal length of a request id
+const maxRequestIDLength = 64
+
+// JSON schema of the wire protocol
+//
+//go:embed protocol.schema.json
+var protocolSchema []byte
+
+// Fields of the envelope and of its event given by the protocol schema
+var (
+	envelopeFields = []string{"version", "kind", "requestId", "event"}
+	eventFields    = []string{"type", "data"}
+)
+
+// Message kind
+type MessageKind string
+
+const (
+	// Message from the client, expects a response
+	KindRequest MessageKind = "request"
+	// Response of the server to the request
+	KindResponse MessageKind = "response"
+	// Message from the server which is not a response
+	KindPush MessageKind = "push"
+)
+
+// Game state push
+const EventTypeState game.EventType = "state"
+
+const (
+	// Message doesn't follow the protocol
+	ErrorCodeInvalidMessage game.ErrorCode = "invalidMessage"
+	// Room settings are inconsistent
+	ErrorCodeInvalidSettings game.ErrorCode = "invalidSettings"
+	// There is no room with the given code
+	ErrorCodeRoomNotFound game.ErrorCode = "roomNotFound"
+	// Room is full
+	ErrorCodeRoomFull game.ErrorCode = "roomFull"
+	// Room is closed
+	ErrorCodeRoomClosed game.ErrorCode = "roomClosed"
+	// Client is not in a room
+	ErrorCodeNotInRoom game.ErrorCode = "notInRoom"
+	// Client is already in a room
+	ErrorCodeAlreadyInRoom game.ErrorCode = "alreadyInRoom"
+	// Game in the room is already started
+	ErrorCodeAlreadyStarted game.ErrorCode = "alreadyStarted"
+	// Session token doesn't match any seat
+	ErrorCodeInvalidToken game.ErrorCode = "invalidToken"
+	// Client isn't allowed to make the request
+	ErrorCodeForbidden game.ErrorCode = "forbidden"
+	// Chat message is rejected
+	ErrorCodeChatRejected game.ErrorCode = "chatRejected"
+	// Too many requests
+	ErrorCodeRateLimited game.ErrorCode = "rateLimited"
+)
+
+// Message
+//
+// Envelope of every message sent over the websocket
+type Message struct {
+	Version   int         `json:"version"`
+	Kind      MessageKind `json:"kind"`
+	RequestID string      `json:"requestId,omitempty"`
+	Event     *game.Event `json:"event"`
+}
+
+// Decode and validate request message against the protocol schema
+func decodeRequest(data []byte) (*Message, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var m Message
+	if err := decoder.Decode(&m); err != nil {
+		return nil, game.NewError(
+			ErrorCodeInvalidMessage, "invalid message: %v", err,
+		)
+	}
+
+	// The message is decoded, but the decoder matches field names
+	// case-insensitively while the schema doesn't
+	var envelope map[string]json.RawMessage
+	json.Unmarshal(data, &envelope)
+	if err := checkFields(envelope, envelopeFields); err != nil {
+		return &m, err
+	}
+
+	var event map[string]json.RawMessage
+	json.Unmarshal(envelope["event"], &event)
+	if err := checkFields(event, eventFields); err != nil {
+		return &m, err
+	}
+
+	if m.Version != ProtocolVersion {
+		return &m, game.NewError(
+			ErrorCodeInvalidMessage,
+			"unsupported protocol version %v, expected %v",
+			m.Version, ProtocolVersion,
+		)
+	}
+
+	if m.Kind != KindRequest {
+		return &m, game.NewError(
+			ErrorCodeInvalidMessage, "message kind must be %v", KindRequest,
+		)
+	}
+
+	if m.RequestID == "" || utf8.RuneCountInString(m.RequestID) > maxRequestIDLength {
+		return &m, game.NewError(
+			ErrorCodeInvalidMessage,
+			"request id must have from 1 to %v characters", maxRequestIDLength,
+		)
+	}
+
+	if m.Event == nil || m.Event.Type == "" {
+		return &m, game.NewError(
+			ErrorCodeInvalidMessage, "request must contain an event with a type",
+		)
+	}
+
+	return &m, nil
+}
+
+// Check that the object has only the allowed fields
+func checkFields(object map[string]json.RawMessage, allowed []string) error {
+	for field := range object {
+		known := false
+		for _, a := range allowed {
+			known = known || field == a
+		}
+
+		if !known {
+			return game.NewError(
+				ErrorCodeInvalidMessage, "invalid message: unknown field %q", field,
+			)
+		}
+	}
+
+	return nil
+}
+
+// Encode response to the request
+func responseMessage(requestID string, e *game.Event) []byte {
+	message, _ := json.Marshal(Message{
+		Version:   ProtocolVersion,
+		Kind:      KindResponse,
+		RequestID: requestID,
+		Event:     e,
+	})
+	return message
+}
+
+// Encode push message
+func pushMessage(e *game.Event) []byte {
+	message, _ := json.Marshal(Message{
+		Version: ProtocolVersion,
+		Kind:    KindPush,
+		Event:   e,
+	})
+	return message
+}
+
+// Encode game state push message
+func statePush(s *game.State) []byte {
+	return pushMessage(game.NewEvent(EventTypeState, s))
+}
@@ -0,0 +1,241 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Leaf rules of the schema by their paths, annotations are skipped
+func schemaRules(path string, node interface{}, rules map[string]string) {
+	object, ok := node.(map[string]interface{})
+	if !ok || len(object) == 0 {
+		value, _ := json.Marshal(node)
+		rules[path] = string(value)
+		return
+	}
+
+	for key, child := range object {
+		switch key {
+		case "$schema", "$id", "title", "description":
+			continue
+		}
+		schemaRules(strings.TrimPrefix(path+"."+key, "."), child, rules)
+	}
+}
+
+// Every rule of the protocol schema is checked by decodeRequest
+//
+// A rule added to the schema or changed in it fails the test until
+// the decoder and the decoding cases below follow it
+func TestProtocolSchemaRules(t *testing.T) {
+	var schema interface{}
+	if err := json.Unmarshal(protocolSchema, &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+
+	got := map[string]string{}
+	schemaRules("", schema, got)
+
+	expected := map[string]interface{}{
+		"type":                                  "object",
+		"additionalProperties":                  false,
+		"required":                              []string{"version", "kind", "event"},
+		"properties.version.const":              ProtocolVersion,
+		"properties.kind.enum":                  []MessageKind{KindRequest, KindResponse, KindPush},
+		"properties.requestId.type":             "string",
+		"properties.requestId.minLength":        1,
+		"properties.requestId.maxLength":        maxRequestIDLength,
+		"properties.event.$ref":                 "#/$defs/event",
+		"if.properties.kind.enum":               []MessageKind{KindRequest, KindResponse},
+		"then.required":                         []string{"requestId"},
+		"$defs.event.type":                      "object",
+		"$defs.event.additionalProperties":      false,
+		"$defs.event.required":                  []string{"type"},
+		"$defs.event.properties.type.type":      "string",
+		"$defs.event.properties.type.minLength": 1,
+		"$defs.event.properties.data":           map[string]interface{}{},
+	}
+
+	want := map[string]string{}
+	for path, value := range expected {
+		encoded, _ := json.Marshal(value)
+		want[path] = string(encoded)
+	}
+
+	for path, value := range got {
+		if want[path] != value {
+			t.Errorf("schema rule %v is %v, decoder follows %v", path, value, want[path])
+		}
+	}
+	for path, value := range want {
+		if _, ok := got[path]; !ok {
+			t.Errorf("schema has no rule %v, decoder follows %v", path, value)
+		}
+	}
+
+	// Fields allowed by the decoder are the schema properties
+	for _, fields := range []struct {
+		path    string
+		allowed []string
+	}{
+		{"properties.", envelopeFields},
+		{"$defs.event.properties.", eventFields},
+	} {
+		for _, f := range fields.allowed {
+			found := false
+			for path := range got {
+				found = found || strings.HasPrefix(path, fields.path+f+".") || path == fields.path+f
+			}
+			if !found {
+				t.Errorf("field %v is not in the schema %v", f, fields.path)
+			}
+		}
+	}
+}
+
+// Requests accepted and rejected by the protocol schema
+func TestDecodeRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		valid   bool
+	}{
+		{
+			"request",
+			`{"version":1,"kind":"request","requestId":"1","event":{"type":"draw"}}`,
+			true,
+		},
+		{
+			"request with data",
+			`{"version":1,"kind":"request","requestId":"1","event":{"type":"chat","data":{"text":"hi"}}}`,
+			true,
+		},
+		{
+			"request with null data",
+			`{"version":1,"kind":"request","requestId":"1","event":{"type":"draw","data":null}}`,
+			true,
+		},
+		{
+			"longest request id",
+			`{"version":1,"kind":"request","requestId":"` + strings.Repeat("a", maxRequestIDLength) + `","event":{"type":"draw"}}`,
+			true,
+		},
+		{
+			"longest request id of multibyte characters",
+			`{"version":1,"kind":"request","requestId":"` + strings.Repeat("я", maxRequestIDLength) + `","event":{"type":"draw"}}`,
+			true,
+		},
+		{
+			"not an object",
+			`[]`,
+			false,
+		},
+		{
+			"no version",
+			`{"kind":"request","requestId":"1","event":{"type":"draw"}}`,
+			false,
+		},
+		{
+			"wrong version",
+			`{"version":2,"kind":"request","requestId":"1","event":{"type":"draw"}}`,
+			false,
+		},
+		{
+			"unknown kind",
+			`{"version":1,"kind":"command","requestId":"1","event":{"type":"draw"}}`,
+			false,
+		},
+		{
+			"no request id",
+			`{"version":1,"kind":"request","event":{"type":"draw"}}`,
+			false,
+		},
+		{
+			"empty request id",
+			`{"version":1,"kind":"request","requestId":"","event":{"type":"draw"}}`,
+			false,
+		},
+		{
+			"too long request id",
+			`{"version":1,"kind":"request","requestId":"` + strings.Repeat("a", maxRequestIDLength+1) + `","event":{"type":"draw"}}`,
+			false,
+		},
+		{
+			"request id is not a string",
+			`{"version":1,"kind":"request","requestId":1,"event":{"type":"draw"}}`,
+			false,
+		},
+		{
+			"no event",
+			`{"version":1,"kind":"request","requestId":"1"}`,
+			false,
+		},
+		{
+			"null event",
+			`{"version":1,"kind":"request","requestId":"1","event":null}`,
+			false,
+		},
+		{
+			"event without type",
+			`{"version":1,"kind":"request","requestId":"1","event":{"data":{}}}`,
+			false,
+		},
+		{
+			"empty event type",
+			`{"version":1,"kind":"request","requestId":"1","event":{"type":""}}`,
+			false,
+		},
+		{
+			"unknown envelope field",
+			`{"version":1,"kind":"request","requestId":"1","event":{"type":"draw"},"extra":1}`,
+			false,
+		},
+		{
+			"unknown event field",
+			`{"version":1,"kind":"request","requestId":"1","event":{"type":"draw","extra":1}}`,
+			false,
+		},
+		{
+			"envelope field in other case",
+			`{"Version":1,"kind":"request","requestId":"1","event":{"type":"draw"}}`,
+			false,
+		},
+		{
+			"event field in other case",
+			`{"version":1,"kind":"request","requestId":"1","event":{"Type":"draw"}}`,
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := decodeRequest([]byte(test.message))
+
+			if test.valid && err != nil {
+				t.Errorf("valid request is rejected: %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Errorf("invalid request is accepted")
+			}
+		})
+	}
+}
+
+// Messages valid by the schema which aren't requests
+func TestDecodeRequestRejectsServerMessages(t *testing.T) {
+	messages := []string{
+		`{"version":1,"kind":"response","requestId":"1","event":{"type":"success"}}`,
+		`{"version":1,"kind":"push","event":{"type":"state"}}`,
+	}
+
+	for _, message := range messages {
+		if _, err := decodeRequest([]byte(message)); err == nil {
+			t.Errorf("server message %v is accepted as a request", message)
+		}
+	}
+}
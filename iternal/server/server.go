@@ -5,12 +5,17 @@
 package server
 
 import (
+	"net/http"
+
+	"github.com/eightlay/rummikub-server/iternal/game"
 	"github.com/gin-gonic/gin"
 )
 
 // Start game server
-func StartServer(addr string) {
-	m := newManager()
+//
+// Chat filters are applied to every chat message in the given order
+func StartServer(addr string, chatFilters ...ChatFilter) {
+	m := newManager(chatFilters)
 
 	r := gin.New()
 	r.GET("/ws", func(m *Manager) gin.HandlerFunc {
@@ -19,5 +24,78 @@ func StartServer(addr string) {
 		})
 	}(m))
 
+	r.GET("/protocol.schema.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/schema+json", protocolSchema)
+	})
+
+	r.GET("/rooms", func(c *gin.Context) {
+		c.JSON(http.StatusOK, m.openRooms())
+	})
+
+	r.POST("/rooms", func(c *gin.Context) {
+		settings := defaultRoomSettings()
+		if err := c.ShouldBindJSON(&settings); err != nil {
+			err = game.NewError(game.ErrorCodeInvalidData, "%v", err)
+			c.JSON(http.StatusBadRequest, errorEvent(err).Data)
+			return
+		}
+
+		hub, err := m.createRoom(settings)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, errorEvent(err).Data)
+			return
+		}
+
+		c.JSON(http.StatusCreated, hub.info())
+	})
+
+	r.GET("/rooms/:code", func(c *gin.Context) {
+		hub, err := m.hubByCode(c.Param("code"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, errorEvent(err).Data)
+			return
+		}
+
+		c.JSON(http.StatusOK, hub.info())
+	})
+
+	r.POST("/rooms/:code/start", func(c *gin.Context) {
+		var e EventStartRoom
+		if err := c.ShouldBindJSON(&e); err != nil {
+			err = game.NewError(game.ErrorCodeInvalidData, "%v", err)
+			c.JSON(http.StatusBadRequest, errorEvent(err).Data)
+			return
+		}
+
+		hub, err := m.hubByCode(c.Param("code"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, errorEvent(err).Data)
+			return
+		}
+
+		err = hub.request(hub.start, &hubRequest{token: e.Token})
+		if err != nil {
+			c.JSON(startStatus(err), errorEvent(err).Data)
+			return
+		}
+
+		c.JSON(http.StatusOK, hub.info())
+	})
+
 	r.Run(addr)
 }
+
+// HTTP status of the failed start request
+//
+// Only the host may start the game, other errors mean
+// the room is not in the state to be started
+func startStatus(err error) int {
+	switch game.ErrorCodeOf(err) {
+	case ErrorCodeForbidden:
+		return http.StatusForbidden
+	case game.ErrorCodeWrongPhase, game.ErrorCodePlayersNumber, ErrorCodeRoomClosed:
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package solver
+
+import (
+	"github.com/eightlay/rummikub-server/iternal/game"
+)
+
+// Hinter suggesting the solution of the hand and the field
+//
+// Nothing is suggested if the field is invalid
+// in the middle of the player's turn
+type Hinter struct {
+	Options Options
+}
+
+func (h Hinter) Hint(
+	rules game.RuleSet, hand []*game.Piece, field []game.FieldCombination, meld bool,
+) *game.Hint {
+	hint := &game.Hint{
+		Replaced:     []int{},
+		Combinations: [][]string{},
+		Played:       []string{},
+	}
+
+	opts := h.Options
+	table := [][]*game.Piece{}
+
+	if meld {
+		opts.Goal = MaxPoints
+	} else {
+		for _, c := range field {
+			table = append(table, c.Pieces)
+		}
+	}
+
+	s, err := Solve(rules, hand, table, opts)
+	if err != nil || s.Pieces == 0 {
+		return hint
+	}
+
+	if meld {
+		sum := 0
+		for _, c := range s.Combinations {
+			sum += c.Value()
+		}
+		if sum < rules.InitialMeldSum {
+			return hint
+		}
+	}
+
+	solved := map[string]bool{}
+	for _, c := range s.Combinations {
+		solved[game.PiecesKey(c.Pieces)] = true
+	}
+
+	kept := map[string]bool{}
+	for _, c := range table {
+		kept[game.PiecesKey(c)] = solved[game.PiecesKey(c)]
+	}
+
+	if !meld {
+		for _, c := range field {
+			if !kept[game.PiecesKey(c.Pieces)] {
+				hint.Replaced = append(hint.Replaced, c.ID)
+			}
+		}
+	}
+
+	for _, c := range s.Combinations {
+		if !kept[game.PiecesKey(c.Pieces)] {
+			hint.Combinations = append(hint.Combinations, game.PiecesIDs(c.Pieces))
+		}
+	}
+
+	for _, p := range s.Played {
+		hint.Played = append(hint.Played, p.ID)
+	}
+
+	return hint
+}
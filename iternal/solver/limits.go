@@ -0,0 +1,50 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package solver
+
+import "time"
+
+// Number of nodes between the time budget checks
+const budgetCheckPeriod int = 256
+
+// Limits of the whole solving: building the candidates and the search
+type limits struct {
+	opts Options
+
+	started time.Time
+	nodes   int
+	stopped bool
+}
+
+// Start counting the limits of the options
+func newLimits(opts Options) *limits {
+	return &limits{opts: opts, started: time.Now()}
+}
+
+// Check if the time budget is exhausted
+func (l *limits) expired() bool {
+	if !l.stopped && l.opts.Budget > 0 && time.Since(l.started) > l.opts.Budget {
+		l.stopped = true
+	}
+
+	return l.stopped
+}
+
+// Count the search node and check if the limits are exhausted
+func (l *limits) exhausted() bool {
+	if l.stopped {
+		return true
+	}
+
+	l.nodes += 1
+	if l.opts.MaxNodes > 0 && l.nodes > l.opts.MaxNodes {
+		l.stopped = true
+	}
+	if l.nodes%budgetCheckPeriod == 0 {
+		l.expired()
+	}
+
+	return l.stopped
+}
@@ -0,0 +1,327 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package solver
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/eightlay/rummikub-server/iternal/game"
+)
+
+// Kind of identical pieces
+type kind struct {
+	color  string
+	number int
+}
+
+// Combination which can be made of the pieces
+//
+// Contains indexes of the kinds and the number of jokers
+type candidate struct {
+	kinds  []int
+	jokers int
+}
+
+// Number of pieces in the candidate
+func (c *candidate) size() int {
+	return len(c.kinds) + c.jokers
+}
+
+// Search problem
+//
+// Identical pieces are counted by their kinds. Table pieces
+// are required in the solution, hand pieces are available
+type problem struct {
+	rules  *game.RuleSet
+	limits *limits
+	kinds  []kind
+
+	// Pieces by kinds, table pieces go first
+	pieces [][]*game.Piece
+	jokers []*game.Piece
+	hand   map[*game.Piece]bool
+
+	required        []int
+	available       []int
+	requiredJokers  int
+	availableJokers int
+
+	// Candidates and their indexes by the kinds they contain
+	candidates []*candidate
+	byKind     [][]int
+	withJokers []int
+}
+
+// Create problem from the hand and the table
+func newProblem(
+	rules *game.RuleSet, hand []*game.Piece, table [][]*game.Piece, l *limits,
+) *problem {
+	p := &problem{
+		rules:  rules,
+		limits: l,
+		hand:   map[*game.Piece]bool{},
+	}
+
+	tablePieces := []*game.Piece{}
+	for _, c := range table {
+		tablePieces = append(tablePieces, c...)
+	}
+
+	index := map[kind]int{}
+	for _, piece := range append(sortedByID(tablePieces), sortedByID(hand)...) {
+		if piece.Joker {
+			continue
+		}
+
+		k := kind{string(piece.Color), piece.Number}
+		if _, ok := index[k]; !ok {
+			index[k] = len(p.kinds)
+			p.kinds = append(p.kinds, k)
+		}
+	}
+
+	sort.Slice(p.kinds, func(i, j int) bool {
+		if p.kinds[i].color != p.kinds[j].color {
+			return p.kinds[i].color < p.kinds[j].color
+		}
+		return p.kinds[i].number < p.kinds[j].number
+	})
+	for i, k := range p.kinds {
+		index[k] = i
+	}
+
+	p.pieces = make([][]*game.Piece, len(p.kinds))
+	p.required = make([]int, len(p.kinds))
+	p.available = make([]int, len(p.kinds))
+
+	add := func(piece *game.Piece, required bool) {
+		if piece.Joker {
+			p.jokers = append(p.jokers, piece)
+			p.availableJokers += 1
+			if required {
+				p.requiredJokers += 1
+			}
+			return
+		}
+
+		i := index[kind{string(piece.Color), piece.Number}]
+		p.pieces[i] = append(p.pieces[i], piece)
+		p.available[i] += 1
+		if required {
+			p.required[i] += 1
+		}
+	}
+
+	for _, piece := range sortedByID(tablePieces) {
+		add(piece, true)
+	}
+	for _, piece := range sortedByID(hand) {
+		add(piece, false)
+		p.hand[piece] = true
+	}
+
+	p.generateCandidates()
+
+	return p
+}
+
+// Copy of the pieces sorted by their ids
+func sortedByID(pieces []*game.Piece) []*game.Piece {
+	sorted := append([]*game.Piece{}, pieces...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
+
+// Generate every group and run which can be made of the pieces
+//
+// Candidates are ordered from the largest ones, so good
+// solutions are found early. When the time budget is exhausted
+// the candidates found before that are kept
+func (p *problem) generateCandidates() {
+	seen := map[string]bool{}
+	add := func(kinds []int, jokers int) {
+		if len(kinds) == 0 || p.limits.expired() {
+			return
+		}
+
+		c := &candidate{append([]int{}, kinds...), jokers}
+		sort.Ints(c.kinds)
+
+		key := fmt.Sprint(c.kinds, c.jokers)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+
+		if p.valid(c) {
+			p.candidates = append(p.candidates, c)
+		}
+	}
+
+	p.generateGroups(add)
+	p.generateRuns(add)
+
+	sort.SliceStable(p.candidates, func(i, j int) bool {
+		ci, cj := p.candidates[i], p.candidates[j]
+		if ci.size() != cj.size() {
+			return ci.size() > cj.size()
+		}
+		return ci.jokers < cj.jokers
+	})
+
+	p.byKind = make([][]int, len(p.kinds))
+	for i, c := range p.candidates {
+		for _, k := range c.kinds {
+			p.byKind[k] = append(p.byKind[k], i)
+		}
+		if c.jokers > 0 {
+			p.withJokers = append(p.withJokers, i)
+		}
+	}
+}
+
+// Check if the candidate is valid under the rule set
+func (p *problem) valid(c *candidate) bool {
+	pieces := append([]*game.Piece{}, p.jokers[:c.jokers]...)
+	for _, k := range c.kinds {
+		pieces = append(pieces, p.pieces[k][0])
+	}
+
+	return p.rules.ValidCombination(pieces, nil) != nil
+}
+
+// Generate groups: pieces of one number and different colors
+func (p *problem) generateGroups(add func(kinds []int, jokers int)) {
+	for n := game.MinNumber; n <= game.MaxNumber; n++ {
+		kinds := []int{}
+		for i, k := range p.kinds {
+			if k.number == n {
+				kinds = append(kinds, i)
+			}
+		}
+
+		for _, subset := range subsets(kinds) {
+			for j := 0; j <= p.availableJokers; j++ {
+				size := len(subset) + j
+				if size >= p.rules.MinGroupSize && size <= p.rules.MaxGroupSize {
+					add(subset, j)
+				}
+			}
+		}
+	}
+}
+
+// Generate runs: consecutive numbers of one color
+//
+// Jokers take the missing numbers and may replace existing
+// pieces which are needed in other combinations
+func (p *problem) generateRuns(add func(kinds []int, jokers int)) {
+	byColor := map[string]map[int]int{}
+	colors := []string{}
+	for i, k := range p.kinds {
+		if _, ok := byColor[k.color]; !ok {
+			byColor[k.color] = map[int]int{}
+			colors = append(colors, k.color)
+		}
+		byColor[k.color][k.number] = i
+	}
+
+	for _, c := range colors {
+		for start := game.MinNumber; start <= game.MaxNumber; start++ {
+			for end := start + p.rules.MinRunSize - 1; end <= game.MaxNumber; end++ {
+				present := []int{}
+				for n := start; n <= end; n++ {
+					if i, ok := byColor[c][n]; ok {
+						present = append(present, i)
+					}
+				}
+
+				missing := end - start + 1 - len(present)
+				if missing > p.availableJokers {
+					continue
+				}
+
+				for _, subset := range removals(present, p.availableJokers-missing) {
+					add(subset, end-start+1-len(subset))
+				}
+			}
+		}
+	}
+}
+
+// Every subset of the items made by removing at most max of them
+func removals(items []int, max int) [][]int {
+	result := [][]int{items}
+	if max == 0 || len(items) == 0 {
+		return result
+	}
+
+	for i := range items {
+		rest := append(append([]int{}, items[:i]...), items[i+1:]...)
+		for _, s := range removals(rest[i:], max-1) {
+			result = append(result, append(append([]int{}, rest[:i]...), s...))
+		}
+	}
+
+	return result
+}
+
+// Every subset of the items
+func subsets(items []int) [][]int {
+	result := [][]int{{}}
+
+	for _, item := range items {
+		for _, s := range result {
+			result = append(result, append(append([]int{}, s...), item))
+		}
+	}
+
+	return result
+}
+
+// Build the solution from the chosen candidates
+func (p *problem) solution(chosen []int, complete bool) (*Solution, error) {
+	next := make([]int, len(p.kinds))
+	nextJoker := 0
+
+	s := &Solution{
+		Combinations: []*game.Combination{},
+		Played:       []*game.Piece{},
+		Complete:     complete,
+	}
+
+	for _, ci := range chosen {
+		c := p.candidates[ci]
+		pieces := []*game.Piece{}
+
+		for _, k := range c.kinds {
+			pieces = append(pieces, p.pieces[k][next[k]])
+			next[k] += 1
+		}
+		for j := 0; j < c.jokers; j++ {
+			pieces = append(pieces, p.jokers[nextJoker])
+			nextJoker += 1
+		}
+
+		combination := p.rules.ValidCombination(pieces, nil)
+		if combination == nil {
+			return nil, fmt.Errorf("solver made invalid combination %v", c)
+		}
+		s.Combinations = append(s.Combinations, combination)
+
+		for _, piece := range pieces {
+			if p.hand[piece] {
+				s.Played = append(s.Played, piece)
+				s.Pieces += 1
+				s.Points += pieceValue(piece)
+			}
+		}
+	}
+
+	return s, nil
+}
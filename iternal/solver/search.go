@@ -0,0 +1,168 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package solver
+
+import (
+	"github.com/eightlay/rummikub-server/iternal/game"
+)
+
+// Depth first search over the sets of candidates
+//
+// Table pieces are covered first, then every hand kind is
+// either covered by one more candidate or left in the hand
+type search struct {
+	p      *problem
+	opts   Options
+	limits *limits
+
+	// Used pieces of every kind and chosen candidates
+	used       []int
+	usedJokers int
+	chosen     []int
+
+	// Weights of the pieces and the score of all of them
+	weights     []int
+	jokerWeight int
+	total       int
+
+	best      []int
+	bestScore int
+	complete  bool
+}
+
+// Create search for the problem
+func newSearch(p *problem, l *limits) *search {
+	s := &search{
+		p:       p,
+		opts:    l.opts,
+		limits:  l,
+		used:    make([]int, len(p.kinds)),
+		weights: make([]int, len(p.kinds)),
+	}
+
+	for i, k := range p.kinds {
+		s.weights[i] = s.weight(k.number)
+		s.total += p.available[i] * s.weights[i]
+	}
+	s.jokerWeight = s.weight(game.JokerValue)
+	s.total += p.availableJokers * s.jokerWeight
+
+	return s
+}
+
+// Weight of the piece with the value according to the goal
+func (s *search) weight(value int) int {
+	if s.opts.Goal == MaxPoints {
+		return value*1000 + 1
+	}
+	return 10000 + value
+}
+
+// Run the search
+func (s *search) run() {
+	s.bestScore = -1
+	s.step(-1, 0, 0)
+	s.complete = !s.limits.stopped
+}
+
+// Score of the used pieces
+func (s *search) score() int {
+	score := s.usedJokers * s.jokerWeight
+	for i, n := range s.used {
+		score += n * s.weights[i]
+	}
+	return score
+}
+
+// Make one search step
+//
+// The anchor is the kind the previous candidate was chosen for,
+// candidates of the same anchor are chosen in the increasing
+// order of their indexes, so every set is visited only once.
+// Hand kinds lower than the floor are not covered anymore
+func (s *search) step(anchor int, from int, floor int) {
+	// Nothing is better than playing every piece
+	if s.limits.exhausted() || s.bestScore == s.total {
+		return
+	}
+
+	// Table pieces must stay on the table
+	for k := range s.p.kinds {
+		if s.used[k] < s.p.required[k] {
+			s.cover(s.p.byKind[k], k, anchor, from, floor)
+			return
+		}
+	}
+
+	joker := len(s.p.kinds)
+	if s.usedJokers < s.p.requiredJokers {
+		s.cover(s.p.withJokers, joker, anchor, from, floor)
+		return
+	}
+
+	if score := s.score(); score > s.bestScore {
+		s.bestScore = score
+		s.best = append([]int{}, s.chosen...)
+	}
+
+	for k := floor; k < len(s.p.kinds); k++ {
+		if s.used[k] < s.p.available[k] {
+			s.cover(s.p.byKind[k], k, anchor, from, k)
+			if !s.limits.stopped {
+				s.step(anchor, from, k+1)
+			}
+			return
+		}
+	}
+}
+
+// Try every candidate which covers the kind
+func (s *search) cover(candidates []int, k int, anchor int, from int, floor int) {
+	for _, ci := range candidates {
+		if k == anchor && ci < from {
+			continue
+		}
+
+		c := s.p.candidates[ci]
+		if !s.fits(c) {
+			continue
+		}
+
+		s.apply(c, 1)
+		s.chosen = append(s.chosen, ci)
+
+		s.step(k, ci, floor)
+
+		s.chosen = s.chosen[:len(s.chosen)-1]
+		s.apply(c, -1)
+
+		if s.limits.stopped || s.bestScore == s.total {
+			return
+		}
+	}
+}
+
+// Check if there are enough pieces for the candidate
+func (s *search) fits(c *candidate) bool {
+	if s.usedJokers+c.jokers > s.p.availableJokers {
+		return false
+	}
+
+	for _, k := range c.kinds {
+		if s.used[k] >= s.p.available[k] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Add or remove the candidate's pieces
+func (s *search) apply(c *candidate, sign int) {
+	s.usedJokers += sign * c.jokers
+	for _, k := range c.kinds {
+		s.used[k] += sign
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package solver
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eightlay/rummikub-server/iternal/game"
+)
+
+// Goal of the search
+type Goal int
+
+const (
+	// Play as many pieces as possible, ties are broken by points
+	MaxPieces Goal = iota
+	// Play pieces of the greatest value, ties are broken by pieces
+	MaxPoints
+)
+
+// Search options
+//
+// The search stops when the time budget or the nodes limit is
+// exhausted, zero means no limit. The result depends only on the
+// input and the options unless the time budget is exhausted
+type Options struct {
+	Goal     Goal
+	Budget   time.Duration
+	MaxNodes int
+}
+
+// Solution
+//
+// Contains the new table made of the table and the played pieces.
+// Complete is false if the search was stopped by the limits, then
+// the solution is the best one found before that
+type Solution struct {
+	Combinations []*game.Combination `json:"combinations"`
+	Played       []*game.Piece       `json:"played"`
+	Pieces       int                 `json:"pieces"`
+	Points       int                 `json:"points"`
+	Complete     bool                `json:"complete"`
+}
+
+// Find the best play of the hand's pieces
+//
+// Every table piece stays on the table, but the table
+// can be rearranged into other combinations
+func Solve(
+	rules game.RuleSet, hand []*game.Piece, table [][]*game.Piece, opts Options,
+) (*Solution, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	current := []*game.Combination{}
+	for i, pieces := range table {
+		c := rules.ValidCombination(pieces, nil)
+		if c == nil {
+			return nil, fmt.Errorf("table combination %v is invalid", i)
+		}
+		current = append(current, c)
+	}
+
+	l := newLimits(opts)
+	p := newProblem(&rules, hand, table, l)
+	s := newSearch(p, l)
+	s.run()
+
+	// Nothing was found in time, the table is kept as it is
+	if s.best == nil {
+		return &Solution{
+			Combinations: current,
+			Played:       []*game.Piece{},
+			Complete:     s.complete,
+		}, nil
+	}
+
+	return p.solution(s.best, s.complete)
+}
+
+// Value of the piece
+//
+// Jokers are valued as if they were left in the hand
+func pieceValue(p *game.Piece) int {
+	if p.Joker {
+		return game.JokerValue
+	}
+	return p.Number
+}
@@ -0,0 +1,223 @@
+// Copyright 2022 eightlay (github.com/eightlay). All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package solver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eightlay/rummikub-server/iternal/game"
+)
+
+// Pieces in the notation like "r5 k13 J", ids start with the prefix
+func testPieces(prefix string, notation string) []*game.Piece {
+	pieces := []*game.Piece{}
+
+	for i, s := range strings.Fields(notation) {
+		p := &game.Piece{ID: fmt.Sprintf("%v%v-%v", prefix, i, s)}
+
+		switch s[0] {
+		case 'J':
+			p.Number, p.Color, p.Joker = game.JokerNumber, game.JokerColor, true
+		case 'k':
+			p.Color = "black"
+		case 'r':
+			p.Color = "red"
+		case 'b':
+			p.Color = "blue"
+		case 'o':
+			p.Color = "orange"
+		}
+
+		if !p.Joker {
+			p.Number, _ = strconv.Atoi(s[1:])
+		}
+
+		pieces = append(pieces, p)
+	}
+
+	return pieces
+}
+
+// Table in the notation like "r5 r6 r7 | k1 b1 o1"
+func testTable(notation string) [][]*game.Piece {
+	table := [][]*game.Piece{}
+
+	for i, c := range strings.Split(notation, "|") {
+		if pieces := testPieces(fmt.Sprintf("t%v.", i), c); len(pieces) > 0 {
+			table = append(table, pieces)
+		}
+	}
+
+	return table
+}
+
+// Check that every table piece stays in the solution's combinations
+func checkTableKept(t *testing.T, table [][]*game.Piece, s *Solution) {
+	t.Helper()
+
+	kept := map[string]bool{}
+	for _, c := range s.Combinations {
+		for _, p := range c.Pieces {
+			kept[p.ID] = true
+		}
+	}
+
+	for _, c := range table {
+		for _, p := range c {
+			if !kept[p.ID] {
+				t.Errorf("table piece %v is not kept", p.ID)
+			}
+		}
+	}
+}
+
+// Key of the solution's combinations and played pieces
+func solutionKey(s *Solution) string {
+	keys := []string{game.PiecesKey(s.Played)}
+	for _, c := range s.Combinations {
+		keys = append(keys, game.PiecesKey(c.Pieces))
+	}
+
+	return strings.Join(keys, "|")
+}
+
+func TestSolve(t *testing.T) {
+	manyJokers := game.DefaultRuleSet()
+	manyJokers.JokersPerDeck = 4
+
+	cases := []struct {
+		name   string
+		rules  game.RuleSet
+		hand   string
+		table  string
+		goal   Goal
+		pieces int
+		points int
+	}{
+		{
+			name:   "nothing to play",
+			hand:   "r5 b9 k13",
+			pieces: 0,
+			points: 0,
+		},
+		{
+			name:   "run from the hand",
+			hand:   "r5 r6 r7 b9",
+			pieces: 3,
+			points: 18,
+		},
+		{
+			name:   "group with joker",
+			hand:   "r5 b5 J k11",
+			pieces: 3,
+			points: 10 + game.JokerValue,
+		},
+		{
+			name:   "table run extended",
+			hand:   "r8 b2",
+			table:  "r5 r6 r7",
+			pieces: 1,
+			points: 8,
+		},
+		{
+			name:   "table run split into group",
+			hand:   "b5 k5",
+			table:  "r5 r6 r7 r8",
+			pieces: 2,
+			points: 10,
+		},
+		{
+			name:   "more pieces",
+			hand:   "r1 r3 r4 J k13 b13",
+			goal:   MaxPieces,
+			pieces: 4,
+			points: 8 + game.JokerValue,
+		},
+		{
+			name:   "more points",
+			hand:   "r1 r3 r4 J k13 b13",
+			goal:   MaxPoints,
+			pieces: 3,
+			points: 26 + game.JokerValue,
+		},
+		{
+			name:   "many jokers",
+			rules:  manyJokers,
+			hand:   "J J J J J J J J r2 r4 r6 r8 r10 r12",
+			pieces: 14,
+			points: 42 + 8*game.JokerValue,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rules := c.rules
+			if rules.DecksNumber == 0 {
+				rules = game.DefaultRuleSet()
+			}
+
+			hand := testPieces("h", c.hand)
+			table := testTable(c.table)
+			opts := Options{Goal: c.goal, Budget: 10 * time.Second}
+
+			s, err := Solve(rules, hand, table, opts)
+			if err != nil {
+				t.Fatalf("solve failed: %v", err)
+			}
+
+			if !s.Complete {
+				t.Errorf("search is not complete")
+			}
+			if s.Pieces != c.pieces || s.Points != c.points {
+				t.Errorf("got %v pieces and %v points, want %v and %v",
+					s.Pieces, s.Points, c.pieces, c.points)
+			}
+			checkTableKept(t, table, s)
+
+			// The same input gives the same result
+			again, err := Solve(rules, hand, table, opts)
+			if err != nil {
+				t.Fatalf("solve failed: %v", err)
+			}
+			if solutionKey(again) != solutionKey(s) {
+				t.Errorf("got %v, then %v", solutionKey(s), solutionKey(again))
+			}
+		})
+	}
+}
+
+func TestSolveLimits(t *testing.T) {
+	rules := game.DefaultRuleSet()
+	rules.JokersPerDeck = 4
+
+	hand := testPieces("h", "J J J J J J J J r2 r4 r6 r8 r10 r12 k3 b3 o3 k7 b7")
+	table := testTable("k5 b5 o5 | r1 r2 r3 r4")
+
+	cases := []struct {
+		name string
+		opts Options
+	}{
+		{"nodes limit", Options{MaxNodes: 1}},
+		{"time budget", Options{Budget: time.Nanosecond}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := Solve(rules, hand, table, c.opts)
+			if err != nil {
+				t.Fatalf("solve failed: %v", err)
+			}
+
+			if s.Complete {
+				t.Errorf("search is complete")
+			}
+			checkTableKept(t, table, s)
+		})
+	}
+}